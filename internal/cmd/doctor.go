@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/intentregistry/intent-cli/internal/config"
+	"github.com/intentregistry/intent-cli/internal/executor"
 	"github.com/intentregistry/intent-cli/internal/httpclient"
 	"github.com/spf13/cobra"
 )
@@ -28,7 +29,8 @@ func DoctorCmd() *cobra.Command {
 		Use:   "doctor",
 		Short: "Check CLI configuration and connectivity",
 		Long: `Run health checks to verify your intent CLI setup.
-Checks configuration, API connectivity, authentication, and shell integration.`,
+Checks configuration, API connectivity, authentication, shell integration,
+file permissions and the result cache.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var results []CheckResult
 			
@@ -47,6 +49,9 @@ Checks configuration, API connectivity, authentication, and shell integration.`,
 			// Check 5: File Permissions
 			results = append(results, checkFilePermissions())
 			
+			// Check 6: Result Cache
+			results = append(results, checkCache())
+			
 			// Print results
 			printResults(results, verbose)
 			
@@ -283,6 +288,40 @@ func checkFilePermissions() CheckResult {
 	return result
 }
 
+func checkCache() CheckResult {
+	result := CheckResult{Name: "Result Cache"}
+	
+	stats, err := executor.NewCache(config.CacheDir()).Stats()
+	if err != nil {
+		result.Status = "⚠️"
+		result.Message = "Cannot read the result cache"
+		result.Details = err.Error()
+		result.Suggestions = []string{fmt.Sprintf("Remove %s to clear the cache", config.CacheDir())}
+		return result
+	}
+	
+	result.Status = "✅"
+	result.Details = fmt.Sprintf("Cache dir: %s, Hits: %d", stats.Dir, stats.Hits)
+	if stats.Entries == 0 {
+		result.Message = "No cached results"
+		return result
+	}
+	result.Message = fmt.Sprintf("%d cached results (%s), %d expired, reused %d times", stats.Entries, formatBytes(stats.Bytes), stats.Expired, stats.Hits)
+	return result
+}
+
+// formatBytes formats a size for people to read
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
 func printResults(results []CheckResult, verbose bool) {
 	fmt.Println("🔍 Intent CLI Health Check")
 	fmt.Println(strings.Repeat("=", 40))
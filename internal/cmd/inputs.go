@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+	"gopkg.in/yaml.v3"
+)
+
+// inputEnvPrefix is prepended to the upper-cased parameter name when
+// reading inputs from the environment, e.g. INTENT_INPUT_CITY
+const inputEnvPrefix = "INTENT_INPUT_"
+
+// inputSources describes where `intent run` reads input values from.
+// Later sources override earlier ones: the secret store (for secret
+// parameters), environment, inputs file (or stdin), then --inputs flags.
+type inputSources struct {
+	Pairs      []string // key=value pairs from --inputs
+	InputsFile string   // JSON or YAML file; "-" reads stdin
+	Stdin      io.Reader
+	Getenv     func(string) string
+	Secret     func(name string) (string, bool, error) // looks up the secret store; nil skips it
+}
+
+// collectInputs merges every input source into the string map consumed by
+// the executor. Structured values are re-encoded as JSON so that typed
+// conversion in the parser sees the same text it would from a flag.
+func collectInputs(intent *parser.Intent, src inputSources) (map[string]string, error) {
+	inputs := make(map[string]string)
+	given := make(map[string]bool) // inputs from the inputs file or flags
+
+	// 0. Stored secrets named after secret parameters
+	if src.Secret != nil {
+		for _, name := range intent.SecretParameters() {
+			value, ok, err := src.Secret(name)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				inputs[name] = value
+			}
+		}
+	}
+
+	// 1. Environment variables for declared parameters
+	if src.Getenv != nil {
+		for _, param := range intent.Parameters {
+			if value := src.Getenv(inputEnvPrefix + envName(param.Name)); value != "" {
+				inputs[param.Name] = value
+			}
+		}
+	}
+
+	// 2. Inputs file or stdin
+	if src.InputsFile != "" {
+		fileInputs, err := readInputsFile(src.InputsFile, src.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range fileInputs {
+			str, err := inputString(value)
+			if err != nil {
+				return nil, fmt.Errorf("input '%s' in %s: %w", key, src.InputsFile, err)
+			}
+			inputs[key], given[key] = str, true
+		}
+	}
+
+	// 3. Explicit --inputs flags
+	for _, input := range src.Pairs {
+		parts := strings.SplitN(input, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid input format '%s', expected 'key=value'", input)
+		}
+		inputs[parts[0]], given[parts[0]] = parts[1], true
+	}
+
+	// Resolve @file references last so they work from every source
+	for _, param := range intent.Parameters {
+		value, ok := inputs[param.Name]
+		if ok && param.Type == "secret" && given[param.Name] {
+			resolved, err := resolveSecretReference(value, src)
+			if err != nil {
+				return nil, fmt.Errorf("input '%s': %w", param.Name, err)
+			}
+			inputs[param.Name] = resolved
+			continue
+		}
+		if !ok || !strings.HasPrefix(value, "@") {
+			continue
+		}
+		if strings.HasPrefix(value, "@@") {
+			// "@@" escapes a literal leading "@"
+			inputs[param.Name] = value[1:]
+			continue
+		}
+		resolved, err := resolveFileReference(value[1:], param.Type)
+		if err != nil {
+			return nil, fmt.Errorf("input '%s': %w", param.Name, err)
+		}
+		inputs[param.Name] = resolved
+	}
+
+	return inputs, nil
+}
+
+// readInputsFile decodes a JSON or YAML object of input values
+func readInputsFile(path string, stdin io.Reader) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		if stdin == nil {
+			return nil, fmt.Errorf("no stdin available for --inputs-file -")
+		}
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read inputs file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".json" || (ext != ".yaml" && ext != ".yml" && json.Valid(data)) {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse inputs file as JSON: %w", err)
+		}
+	} else if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse inputs file as YAML: %w", err)
+	}
+	return values, nil
+}
+
+// inputString renders a decoded value as the text form used by --inputs
+func inputString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// resolveFileReference handles `@path` values. For file-typed parameters
+// the path itself is passed on (made absolute and checked to exist); for
+// any other type the file's contents become the value.
+func resolveFileReference(path, paramType string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("empty @file reference")
+	}
+	if paramType == "file" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return "", fmt.Errorf("referenced file not found: %s", path)
+		}
+		return abs, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read referenced file: %w", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// resolveSecretReference returns the value of a secret given in an inputs
+// file or flag, which must say where to read it from rather than be the
+// value itself, so that it stays out of shell history and files:
+// @path, env:NAME or store:NAME
+func resolveSecretReference(ref string, src inputSources) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "@"):
+		return resolveFileReference(ref[1:], "secret")
+	case strings.HasPrefix(ref, "env:"):
+		name := ref[len("env:"):]
+		value := ""
+		if src.Getenv != nil {
+			value = src.Getenv(name)
+		}
+		if value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(ref, "store:"):
+		name := ref[len("store:"):]
+		if src.Secret == nil {
+			return "", fmt.Errorf("no secret store available for %s", ref)
+		}
+		value, ok, err := src.Secret(name)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("no secret named %q (add it with 'intent secret set %s')", name, name)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("secrets cannot be given as plain values (use @path, env:NAME or store:NAME)")
+}
+
+// envName converts a parameter name into its environment variable suffix
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
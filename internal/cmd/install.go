@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/intentregistry/intent-cli/internal/config"
@@ -45,7 +44,7 @@ func InstallCmd() *cobra.Command {
 
             // Prepare download
             dlDir := filepath.Join(".intent-cache", "downloads")
-            dlPath := filepath.Join(dlDir, sanitizeFilename(name+"-"+version)+".tar.gz")
+            dlPath := filepath.Join(dlDir, pack.SanitizeName(name+"-"+version)+".tar.gz")
             if err := cl.Download(tarball, dlPath); err != nil {
                 return err
             }
@@ -58,7 +57,7 @@ func InstallCmd() *cobra.Command {
             }
 
             // Extract
-            targetDir := filepath.Join(dest, sanitizeFilename(name))
+            targetDir := pack.PackageDir(dest, name)
             fmt.Println("📦 Extracting to", targetDir)
             if err := pack.UntarGz(dlPath, targetDir); err != nil {
                 return err
@@ -81,10 +80,4 @@ func InstallCmd() *cobra.Command {
 	}
 	c.Flags().StringVar(&dest, "dest", "intents", "destination folder")
 	return c
-}
-
-var filenameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._@-]+`)
-
-func sanitizeFilename(s string) string {
-    return filenameSanitizer.ReplaceAllString(s, "-")
 }
\ No newline at end of file
@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
 	"github.com/spf13/cobra"
 )
 
@@ -219,6 +220,18 @@ func scaffoldItpkgJSON(dir, name string) error {
 		},
 	}
 
+	// Carry over metadata declared by the entry intent
+	if entryPoint != "" {
+		if intent, err := parser.ParseITML(entryPath); err == nil {
+			if intent.Description != "" {
+				manifest.Description = intent.Description
+			}
+			if intent.ItmlVersion != "" {
+				manifest.ItmlVersion = intent.ItmlVersion
+			}
+		}
+	}
+
 	if entryPoint == "" {
 		manifest.Type = "lib"
 	} else {
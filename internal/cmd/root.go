@@ -43,6 +43,7 @@ func init() {
 		TestCmd(),
 		WhoamiCmd(),
 		SearchCmd(),
+		SecretCmd(),
 		VersionCmd(),
 		CompletionCmd(),
 	)
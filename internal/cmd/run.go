@@ -1,20 +1,42 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/intentregistry/intent-cli/internal/config"
 	"github.com/intentregistry/intent-cli/internal/executor"
+	"github.com/intentregistry/intent-cli/internal/pack"
 	"github.com/intentregistry/intent-cli/internal/parser"
 	"github.com/spf13/cobra"
 )
 
 func RunCmd() *cobra.Command {
 	var (
-		inputs    []string
-		outputDir string
-		verbose   bool
+		inputs     []string
+		inputsFile string
+		outputDir  string
+		verbose    bool
+		plugins    []string
+		timeout    time.Duration
+		mock        bool
+		trace       string
+		traceFormat string
+		plan        bool
+		record      string
+		replay      string
+		noCache     bool
+		refresh     bool
 	)
 	
 	c := &cobra.Command{
@@ -25,10 +47,69 @@ func RunCmd() *cobra.Command {
 The --inputs flag allows you to pass key-value pairs that will be available
 to the intent during execution. Multiple inputs can be provided.
 
+Inputs are collected from several sources; later sources win:
+  1. Parameter defaults declared in the intent
+  2. Environment variables named INTENT_INPUT_<NAME> (e.g. INTENT_INPUT_CITY)
+  3. --inputs-file: a JSON or YAML object of values ("-" reads stdin)
+  4. --inputs key=value flags
+
+A value of @path passes a checked, absolute path for file-typed parameters
+and the file's contents for any other type. Use @@ for a literal leading @.
+All inputs are validated against the intent's parameters before execution.
+
+Secret parameters are first read from the secret store entry of the same
+name (see intent secret). In an inputs file or --inputs they must name
+where the value is, as @path, env:NAME or store:NAME, never the value
+itself. Secret values are masked in logs, traces, cassettes, cached and
+saved results and error messages.
+
+When the intent belongs to a package, the network and script policies and
+the capabilities in its itpkg.json apply. --plugin adds the workflow steps provided by an
+external binary speaking the JSON-over-stdio plugin protocol.
+
+Ctrl-C (or SIGTERM) stops the running step and ends the execution; --timeout
+does the same once the given time has passed.
+
+The results must provide every output the intent declares, with its type.
+An intent without a script fails unless --mock fills its outputs with
+placeholder values, which is useful while designing an intent.
+
+--plan shows what the execution would do without doing it: the workflow steps
+with their arguments resolved from the inputs and the kinds of their results,
+the HTTP requests they would send and the capabilities they would use.
+
+--record saves every HTTP request and plugin call the execution makes, with
+its response, to a cassette file; --replay serves them back from the
+cassette without touching the network, so runs are repeatable. A replayed
+run fails if it makes a call the cassette does not hold.
+
+Intents declaring a cache duration (e.g. cache: "1h") reuse the results of
+an earlier run with the same inputs until they expire; the cache is kept in
+~/.intent/cache. --no-cache runs the intent without reading or storing
+results, and --refresh runs it and replaces the cached results.
+
+--trace records what the execution did: when each workflow step started and
+ended, its arguments, value or error, and how long it took. The default
+jsonl format writes one JSON event per line; --trace-format otlp-json writes
+an OpenTelemetry trace that tracing tools can import.
+
 Examples:
   intent run my-intent.itml
   intent run my-intent.itml --inputs name=John --inputs age=30
-  intent run my-intent.itml --inputs query="search for cats" --output-dir ./results`,
+  intent run my-intent.itml --inputs-file inputs.yaml
+  cat inputs.json | intent run my-intent.itml --inputs-file -
+  intent run my-intent.itml --inputs source_file=@data.csv
+  intent run my-intent.itml --inputs api_key=env:WEATHER_API_KEY
+  intent run my-intent.itml --inputs query="search for cats" --output-dir ./results
+  intent run my-intent.itml --plugin ./bin/db-steps
+  intent run my-intent.itml --timeout 2m
+  intent run draft.itml --mock
+  intent run my-intent.itml --inputs city=Paris --plan
+  intent run my-intent.itml --record cassette.json
+  intent run my-intent.itml --replay cassette.json
+  intent run my-intent.itml --inputs city=Paris --refresh
+  intent run my-intent.itml --trace trace.jsonl
+  intent run my-intent.itml --trace trace.json --trace-format otlp-json`,
 		Args: cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// Enable file completion for .itml files
@@ -46,16 +127,6 @@ Examples:
 				return fmt.Errorf("intent file not found: %s", itmlFile)
 			}
 			
-			// Parse input parameters
-			inputParams := make(map[string]string)
-			for _, input := range inputs {
-				parts := strings.SplitN(input, "=", 2)
-				if len(parts) != 2 {
-					return fmt.Errorf("invalid input format '%s', expected 'key=value'", input)
-				}
-				inputParams[parts[0]] = parts[1]
-			}
-			
 			if verbose {
 				fmt.Printf("🔍 Parsing intent file: %s\n", itmlFile)
 			}
@@ -73,25 +144,140 @@ Examples:
 				fmt.Printf("📤 Outputs: %d\n", len(intent.Outputs))
 			}
 			
-			// Validate required parameters
-			for _, param := range intent.Parameters {
-				if param.Required && inputParams[param.Name] == "" {
-					return fmt.Errorf("required parameter '%s' not provided", param.Name)
-				}
+			// Collect inputs from the environment, inputs file and flags
+			config.LoadEnvFile()
+			inputParams, err := collectInputs(intent, inputSources{
+				Pairs:      inputs,
+				InputsFile: inputsFile,
+				Stdin:      cmd.InOrStdin(),
+				Getenv:     os.Getenv,
+				Secret:     config.OpenSecretStore(config.SecretsDir()).Get,
+			})
+			if err != nil {
+				return err
+			}
+			
+			// Validate inputs against the intent's parameters
+			if err := executor.ValidateInputs(intent, inputParams); err != nil {
+				return err
 			}
 			
 			if verbose {
 				fmt.Printf("🚀 Executing intent...\n")
 			}
 			
+			// Apply the policies of the package the intent belongs to
+			opts := executor.Options{OutputDir: outputDir, Mock: mock}
+			manifest, manifestPath, err := pack.FindManifest(itmlFile)
+			if err != nil {
+				return err
+			}
+			if manifest != nil {
+				if opts.Network, err = manifest.NetworkPolicy(); err != nil {
+					return fmt.Errorf("%s: %w", manifestPath, err)
+				}
+				if opts.Scripts, err = manifest.ScriptPolicy(); err != nil {
+					return fmt.Errorf("%s: %w", manifestPath, err)
+				}
+				opts.Capabilities = append([]string{}, manifest.Capabilities...)
+				opts.PackageDir = filepath.Dir(manifestPath)
+				if verbose {
+					fmt.Printf("🔒 Package policy: %s\n", manifestPath)
+				}
+			}
+			
+			// Register steps provided by plugin binaries
+			if len(plugins) > 0 {
+				opts.Steps = executor.NewRegistry()
+				for _, plugin := range plugins {
+					names, err := opts.Steps.LoadPlugin(plugin)
+					if err != nil {
+						return err
+					}
+					if verbose {
+						fmt.Printf("🔌 Plugin %s: %s\n", plugin, strings.Join(names, ", "))
+					}
+				}
+			}
+			
+			// Show the plan instead of executing
+			if plan {
+				p, err := executor.PlanIntent(intent, inputParams, opts)
+				if err != nil {
+					return fmt.Errorf("planning failed: %w", err)
+				}
+				printPlan(cmd.OutOrStdout(), p)
+				return nil
+			}
+			
+			if !noCache {
+				opts.Cache = executor.NewCache(config.CacheDir())
+				opts.RefreshCache = refresh
+			}
+			
+			// Record external calls, or replay them from a cassette
+			switch {
+			case record != "":
+				opts.Cassette = executor.NewCassette()
+			case replay != "":
+				if opts.Cassette, err = executor.LoadCassette(replay); err != nil {
+					return fmt.Errorf("failed to load cassette: %w", err)
+				}
+			}
+			
+			// Record the execution's events to the trace file
+			var traceWriter *executor.TraceWriter
+			if trace != "" {
+				f, err := os.Create(trace)
+				if err != nil {
+					return fmt.Errorf("failed to create trace file: %w", err)
+				}
+				defer f.Close()
+				if traceWriter, err = executor.NewTraceWriter(f, traceFormat); err != nil {
+					f.Close()
+					os.Remove(trace)
+					return err
+				}
+				opts.Observer = traceWriter
+			}
+			cacheHit := &cacheObserver{next: opts.Observer}
+			opts.Observer = cacheHit
+			
+			// Stop on Ctrl-C, SIGTERM or the --timeout deadline
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			if timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			
 			// Execute the intent
-			results, err := executor.Execute(intent, inputParams, outputDir)
+			results, err := executor.ExecuteContext(ctx, intent, inputParams, opts)
+			if record != "" {
+				// Calls made before a failure are kept, to reproduce it
+				if saveErr := opts.Cassette.Save(record); saveErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to save cassette: %v\n", saveErr)
+				}
+			}
+			if traceWriter != nil {
+				// The trace is kept when the execution fails, to debug it
+				if traceErr := traceWriter.Close(); traceErr != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to write trace: %v\n", traceErr)
+				}
+			}
+			if errors.Is(err, executor.ErrTimeout) {
+				return fmt.Errorf("execution stopped after %s: %w", timeout, err)
+			}
 			if err != nil {
 				return fmt.Errorf("execution failed: %w", err)
 			}
 			
 			// Display results
 			fmt.Println("✅ Intent executed successfully!")
+			if cacheHit.cached {
+				fmt.Println("♻️  Results reused from the cache (use --refresh to run again)")
+			}
 			fmt.Println()
 			
 			if len(results) > 0 {
@@ -104,14 +290,129 @@ Examples:
 			if outputDir != "" {
 				fmt.Printf("📁 Results saved to: %s\n", outputDir)
 			}
+			if trace != "" {
+				fmt.Printf("🧭 Trace saved to: %s\n", trace)
+			}
+			if record != "" {
+				fmt.Printf("📼 Cassette saved to: %s\n", record)
+			}
 			
 			return nil
 		},
 	}
 	
-	c.Flags().StringSliceVar(&inputs, "inputs", []string{}, "Input parameters as key=value pairs (can be used multiple times)")
+	c.Flags().StringArrayVar(&inputs, "inputs", []string{}, "Input parameters as key=value pairs (can be used multiple times)")
+	c.Flags().StringVar(&inputsFile, "inputs-file", "", "JSON or YAML file with input values ('-' reads stdin)")
 	c.Flags().StringVar(&outputDir, "output-dir", "", "Directory to save output files")
 	c.Flags().BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	c.Flags().StringArrayVar(&plugins, "plugin", []string{}, "Plugin binary providing extra workflow steps (can be used multiple times)")
+	c.Flags().DurationVar(&timeout, "timeout", 0, "Stop the execution after this long (e.g. 30s; 0 means no limit)")
+	c.Flags().BoolVar(&mock, "mock", false, "Fill the outputs of an intent without a script with placeholder values")
+	c.Flags().StringVar(&trace, "trace", "", "File to record the execution's step events to")
+	c.Flags().StringVar(&traceFormat, "trace-format", executor.TraceJSONL, "Trace file format: jsonl or otlp-json")
+	c.Flags().BoolVar(&plan, "plan", false, "Show what the execution would do without running anything")
+	c.Flags().StringVar(&record, "record", "", "Cassette file to record HTTP requests and plugin calls to")
+	c.Flags().StringVar(&replay, "replay", "", "Cassette file to replay HTTP requests and plugin calls from, without network access")
+	c.Flags().BoolVar(&noCache, "no-cache", false, "Neither reuse nor store cached results")
+	c.Flags().BoolVar(&refresh, "refresh", false, "Run the intent even if its results are cached, and cache the new ones")
+	c.MarkFlagsMutuallyExclusive("record", "replay")
+	c.MarkFlagsMutuallyExclusive("no-cache", "refresh")
 	
 	return c
 }
+
+// cacheObserver notes whether the results of an execution came from the
+// cache, passing every event on to next
+type cacheObserver struct {
+	next   executor.Observer
+	cached bool
+}
+
+func (o *cacheObserver) Observe(e executor.Event) {
+	if e.Type == executor.EventExecutionEnd && e.Cached {
+		o.cached = true
+	}
+	if o.next != nil {
+		o.next.Observe(e)
+	}
+}
+
+// printPlan writes a plan for people to read
+func printPlan(w io.Writer, plan *executor.Plan) {
+	fmt.Fprintf(w, "📋 Plan for %s (%s)\n", plan.Intent, plan.Runner)
+	
+	if len(plan.Inputs) > 0 {
+		fmt.Fprintln(w, "\nInputs:")
+		names := make([]string, 0, len(plan.Inputs))
+		for name := range plan.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(w, "  %s: %s\n", name, planValue(plan.Inputs[name]))
+		}
+	}
+	
+	if len(plan.Steps) > 0 {
+		fmt.Fprintln(w, "\nSteps:")
+		for _, step := range plan.Steps {
+			indent := strings.Repeat("  ", step.Depth+1)
+			branch := ""
+			if step.Branch != "" {
+				branch = "(" + step.Branch + ") "
+			}
+			fmt.Fprintf(w, "%s→ %s%s   [%s, returns %s]\n", indent, branch, step.Source, step.ID, step.Returns)
+			for _, arg := range step.Args {
+				value := arg.Expr + " (known at run time)"
+				if arg.Resolved {
+					value = planValue(arg.Value)
+				}
+				fmt.Fprintf(w, "%s    %s = %s\n", indent, arg.Name, value)
+			}
+		}
+	}
+	
+	if plan.Text != "" {
+		fmt.Fprintf(w, "\nText:\n  %s\n", strings.ReplaceAll(plan.Text, "\n", "\n  "))
+	}
+	
+	if len(plan.Requests) > 0 {
+		fmt.Fprintln(w, "\nHTTP requests:")
+		for _, req := range plan.Requests {
+			target := req.URL
+			if !req.Resolved {
+				target += " (known at run time)"
+			}
+			fmt.Fprintf(w, "  %s %s   [%s]\n", req.Method, target, req.Step)
+			if req.Blocked != "" {
+				fmt.Fprintf(w, "    ⛔ blocked: %s\n", req.Blocked)
+			}
+		}
+	}
+	
+	if len(plan.Capabilities) > 0 {
+		fmt.Fprintf(w, "\nCapabilities: %s\n", strings.Join(plan.Capabilities, ", "))
+	}
+	if len(plan.Missing) > 0 {
+		fmt.Fprintf(w, "⚠️  Not declared by the package: %s\n", strings.Join(plan.Missing, ", "))
+	}
+	
+	if len(plan.Outputs) > 0 {
+		outputs := make([]string, len(plan.Outputs))
+		for i, output := range plan.Outputs {
+			outputs[i] = fmt.Sprintf("%s (%s)", output.Name, output.Type)
+		}
+		fmt.Fprintf(w, "\nOutputs: %s\n", strings.Join(outputs, ", "))
+	}
+	
+	fmt.Fprintln(w, "\nNothing was executed.")
+}
+
+// planValue formats a resolved value as JSON
+func planValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
 )
 
 func TestRunCommand_Integration(t *testing.T) {
@@ -109,3 +114,193 @@ func TestRunCommand_FileValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectInputs_Precedence(t *testing.T) {
+	tempDir := t.TempDir()
+
+	intent := &parser.Intent{
+		Parameters: []parser.Parameter{
+			{Name: "city", Type: "string"},
+			{Name: "days", Type: "integer"},
+			{Name: "tags", Type: "array"},
+			{Name: "source", Type: "file"},
+			{Name: "notes", Type: "text"},
+			{Name: "handle", Type: "string"},
+		},
+	}
+
+	inputsFile := filepath.Join(tempDir, "inputs.yaml")
+	if err := os.WriteFile(inputsFile, []byte("city: Paris\ndays: 5\ntags: [a, b]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write inputs file: %v", err)
+	}
+	dataFile := filepath.Join(tempDir, "data.csv")
+	if err := os.WriteFile(dataFile, []byte("a,b\n"), 0644); err != nil {
+		t.Fatalf("Failed to write data file: %v", err)
+	}
+	notesFile := filepath.Join(tempDir, "notes.txt")
+	if err := os.WriteFile(notesFile, []byte("hello notes\n"), 0644); err != nil {
+		t.Fatalf("Failed to write notes file: %v", err)
+	}
+
+	env := map[string]string{
+		"INTENT_INPUT_CITY": "Berlin",
+		"INTENT_INPUT_DAYS": "2",
+	}
+
+	inputs, err := collectInputs(intent, inputSources{
+		Pairs:      []string{"days=7", "source=@" + dataFile, "notes=@" + notesFile, "handle=@@me"},
+		InputsFile: inputsFile,
+		Getenv:     func(k string) string { return env[k] },
+	})
+	if err != nil {
+		t.Fatalf("collectInputs failed: %v", err)
+	}
+
+	want := map[string]string{
+		"city":   "Paris",   // inputs file overrides environment
+		"days":   "7",       // flag overrides inputs file
+		"tags":   `["a","b"]`,
+		"source": dataFile,
+		"notes":  "hello notes",
+		"handle": "@me",
+	}
+	for key, value := range want {
+		if inputs[key] != value {
+			t.Errorf("Input %s: expected %q, got %q", key, value, inputs[key])
+		}
+	}
+}
+
+func TestCollectInputs_Stdin(t *testing.T) {
+	intent := &parser.Intent{Parameters: []parser.Parameter{{Name: "city", Type: "string"}}}
+
+	inputs, err := collectInputs(intent, inputSources{
+		InputsFile: "-",
+		Stdin:      strings.NewReader(`{"city": "Lisbon"}`),
+	})
+	if err != nil {
+		t.Fatalf("collectInputs failed: %v", err)
+	}
+	if inputs["city"] != "Lisbon" {
+		t.Errorf("Expected city from stdin, got %q", inputs["city"])
+	}
+
+	_, err = collectInputs(intent, inputSources{Pairs: []string{"city=@missing.txt"}})
+	if err == nil {
+		t.Error("Expected error for missing @file reference")
+	}
+}
+
+func TestCollectInputs_Secrets(t *testing.T) {
+	tempDir := t.TempDir()
+	keyFile := filepath.Join(tempDir, "key.txt")
+	if err := os.WriteFile(keyFile, []byte("from-file\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	intent := &parser.Intent{Parameters: []parser.Parameter{{Name: "api_key", Type: "secret"}}}
+	env := map[string]string{"WEATHER_KEY": "from-env", "INTENT_INPUT_API_KEY": "from-input-env"}
+	store := map[string]string{"api_key": "from-store", "other": "from-other"}
+	sources := func(pairs ...string) inputSources {
+		return inputSources{
+			Pairs:  pairs,
+			Getenv: func(k string) string { return env[k] },
+			Secret: func(name string) (string, bool, error) {
+				value, ok := store[name]
+				return value, ok, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		pairs []string
+		want  string
+		err   string
+	}{
+		{"environment overrides store", nil, "from-input-env", ""},
+		{"file", []string{"api_key=@" + keyFile}, "from-file", ""},
+		{"named variable", []string{"api_key=env:WEATHER_KEY"}, "from-env", ""},
+		{"named secret", []string{"api_key=store:other"}, "from-other", ""},
+		{"plain value", []string{"api_key=s3cret"}, "", "secrets cannot be given as plain values"},
+		{"unset variable", []string{"api_key=env:NOPE"}, "", "environment variable NOPE is not set"},
+		{"unknown secret", []string{"api_key=store:nope"}, "", `no secret named "nope"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs, err := collectInputs(intent, sources(tt.pairs...))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("Expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("collectInputs failed: %v", err)
+			}
+			if inputs["api_key"] != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, inputs["api_key"])
+			}
+		})
+	}
+
+	delete(env, "INTENT_INPUT_API_KEY")
+	if inputs, err := collectInputs(intent, sources()); err != nil || inputs["api_key"] != "from-store" {
+		t.Errorf("Expected the stored secret, got %q, %v", inputs["api_key"], err)
+	}
+}
+
+func TestRunCommand_ValidatesInputs(t *testing.T) {
+	tempDir := t.TempDir()
+	itmlFile := filepath.Join(tempDir, "weather.itml")
+	src := `intent "Weather"
+inputs:
+  - city (string) required
+  - days (integer) min=1 max=14
+`
+	if err := os.WriteFile(itmlFile, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	cmd := RunCmd()
+	cmd.SetArgs([]string{itmlFile, "--inputs", "days=30", "--inputs", "colour=red"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"city: required parameter not provided", "days: value 30 is greater than maximum 14", "colour: unknown parameter"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in error:\n%v", want, err)
+		}
+	}
+}
+
+func TestRunCommand_Plan(t *testing.T) {
+	tempDir := t.TempDir()
+	itmlFile := filepath.Join(tempDir, "weather.itml")
+	src := `intent "Weather"
+inputs:
+  - city (string) required
+workflow:
+  → forecast = http.get("https://weather.invalid/{city}")
+  → return(temp=forecast.temp)
+`
+	if err := os.WriteFile(itmlFile, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	var out strings.Builder
+	cmd := RunCmd()
+	cmd.SetArgs([]string{itmlFile, "--inputs", "city=Oslo", "--plan"})
+	cmd.SetOut(&out)
+	cmd.SetErr(io.Discard)
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("Expected the plan to succeed without network access, got %v", err)
+	}
+	for _, want := range []string{"Plan for Weather (workflow)", `url = "https://weather.invalid/Oslo"`, "GET https://weather.invalid/Oslo", "Capabilities: http.outbound", "Nothing was executed."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in plan:\n%s", want, out.String())
+		}
+	}
+}
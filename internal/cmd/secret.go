@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+func SecretCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage the secrets given to secret inputs",
+		Long: `Manage the encrypted secret store in ~/.intent.
+
+A secret input of an intent (declared with type secret) is read from the
+store entry of the same name unless the environment or --inputs say
+otherwise. Secret values are never shown by intent run, and are masked in
+logs, traces, cassettes, cached and saved results and error messages.
+
+The store is ~/.intent/secrets.json, encrypted under a key kept beside it
+in ~/.intent/secrets.key. The encryption only protects secrets.json when it
+is shown or copied without the key; anyone who can read your files can
+decrypt it. Both files must be readable by your user only (mode 600);
+the store refuses them otherwise.
+
+Examples:
+  intent secret set api_key < key.txt
+  intent secret set api_key --from-file key.txt
+  intent secret list
+  intent secret rm api_key`,
+	}
+	c.AddCommand(secretSetCmd(), secretListCmd(), secretRmCmd())
+	return c
+}
+
+func secretSetCmd() *cobra.Command {
+	var fromFile string
+	c := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Store a secret, read from stdin or a file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			var value string
+			if fromFile != "" {
+				data, err := os.ReadFile(fromFile)
+				if err != nil {
+					return fmt.Errorf("failed to read secret: %w", err)
+				}
+				value = strings.TrimRight(string(data), "\r\n")
+			} else if fd, ok := terminal(cmd.InOrStdin()); ok {
+				// Typed values are not echoed
+				fmt.Fprintf(cmd.OutOrStdout(), "Enter the value of %s: ", name)
+				data, err := term.ReadPassword(fd)
+				fmt.Fprintln(cmd.OutOrStdout())
+				if err != nil {
+					return fmt.Errorf("failed to read secret: %w", err)
+				}
+				value = string(data)
+			} else {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && err != io.EOF {
+					return fmt.Errorf("failed to read secret: %w", err)
+				}
+				value = strings.TrimRight(line, "\r\n")
+			}
+			if value == "" {
+				return fmt.Errorf("the value of %s is empty", name)
+			}
+
+			if err := config.OpenSecretStore(config.SecretsDir()).Set(name, value); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "🔐 Stored secret %s\n", name)
+			return nil
+		},
+	}
+	c.Flags().StringVar(&fromFile, "from-file", "", "Read the value from a file instead of stdin")
+	return c
+}
+
+func secretListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the names of stored secrets",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			names, err := config.OpenSecretStore(config.SecretsDir()).Names()
+			if err != nil {
+				return err
+			}
+			if len(names) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No secrets stored (add one with 'intent secret set NAME')")
+				return nil
+			}
+			for _, name := range names {
+				fmt.Fprintln(cmd.OutOrStdout(), name)
+			}
+			return nil
+		},
+	}
+}
+
+func secretRmCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "rm NAME",
+		Short: "Remove a stored secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := config.OpenSecretStore(config.SecretsDir()).Delete(args[0])
+			if err != nil {
+				return err
+			}
+			if !removed {
+				return fmt.Errorf("no secret named %q", args[0])
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "🗑️  Removed secret %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+// terminal returns the file descriptor of r if it is an interactive
+// terminal
+func terminal(r io.Reader) (int, bool) {
+	f, ok := r.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0, false
+	}
+	return int(f.Fd()), true
+}
@@ -1,11 +1,15 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/intentregistry/intent-cli/internal/executor"
@@ -72,8 +76,10 @@ Examples:
 				fmt.Printf("📋 Found %d tests\n", len(tests))
 			}
 			
-			// Run tests
-			results, err := runTests(tests, timeout, parallel, verbose)
+			// Run tests, stopping the current one on Ctrl-C or SIGTERM
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			results, err := runTests(ctx, tests, timeout, parallel, verbose)
 			if err != nil {
 				return fmt.Errorf("failed to run tests: %w", err)
 			}
@@ -261,8 +267,9 @@ func discoverTestFile(testPath string) (*TestCase, error) {
 	return &test, nil
 }
 
-// runTests executes all test cases
-func runTests(tests []TestCase, timeout time.Duration, parallel int, verbose bool) (*TestResults, error) {
+// runTests executes all test cases, each within timeout. Tests not yet
+// run when ctx ends are skipped.
+func runTests(ctx context.Context, tests []TestCase, timeout time.Duration, parallel int, verbose bool) (*TestResults, error) {
 	results := &TestResults{
 		Total:   len(tests),
 		Results: make([]TestResult, len(tests)),
@@ -276,7 +283,10 @@ func runTests(tests []TestCase, timeout time.Duration, parallel int, verbose boo
 			fmt.Printf("🧪 Running test: %s\n", test.Name)
 		}
 		
-		result := runSingleTest(test, timeout)
+		result := TestResult{TestCase: test, Status: "skipped", Error: "test run interrupted"}
+		if ctx.Err() == nil {
+			result = runSingleTest(ctx, test, timeout)
+		}
 		results.Results[i] = result
 		
 		switch result.Status {
@@ -297,8 +307,9 @@ func runTests(tests []TestCase, timeout time.Duration, parallel int, verbose boo
 	return results, nil
 }
 
-// runSingleTest executes a single test case
-func runSingleTest(test TestCase, timeout time.Duration) TestResult {
+// runSingleTest executes a single test case, failing it when it runs
+// longer than timeout
+func runSingleTest(ctx context.Context, test TestCase, timeout time.Duration) TestResult {
 	result := TestResult{
 		TestCase: test,
 		Status:   "failed",
@@ -334,7 +345,16 @@ func runSingleTest(test TestCase, timeout time.Duration) TestResult {
 	}
 	
 	// Execute the intent
-	output, err := executor.Execute(intent, inputParams, "")
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	output, err := executor.ExecuteContext(ctx, intent, inputParams, executor.Options{})
+	if errors.Is(err, executor.ErrTimeout) {
+		result.Error = fmt.Sprintf("timed out after %s", timeout)
+		return result
+	}
 	if err != nil {
 		result.Error = fmt.Sprintf("execution failed: %v", err)
 		return result
@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestTestCommand_Integration(t *testing.T) {
@@ -223,3 +228,39 @@ func TestTestCommand_InvalidTestFile(t *testing.T) {
 		t.Errorf("Expected 0 tests due to invalid JSON, found %d", len(tests))
 	}
 }
+
+func TestRunTests_TimeoutAndInterrupt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	
+	path := filepath.Join(t.TempDir(), "slow.itml")
+	src := "intent \"Slow\"\nworkflow:\n  → http.get(\"" + server.URL + "\")\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	tests := []TestCase{{Name: "slow", Type: "unit", Path: path}}
+	
+	start := time.Now()
+	results, err := runTests(context.Background(), tests, 50*time.Millisecond, 1, false)
+	if err != nil {
+		t.Fatalf("runTests failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the test to stop at its timeout, took %v", elapsed)
+	}
+	if results.Failed != 1 || results.Results[0].Error != "timed out after 50ms" {
+		t.Errorf("Expected a timed out failure, got %+v", results.Results[0])
+	}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results, err = runTests(ctx, tests, time.Second, 1, false)
+	if err != nil {
+		t.Fatalf("runTests failed: %v", err)
+	}
+	if results.Skipped != 1 {
+		t.Errorf("Expected interrupted test to be skipped, got %+v", results.Results[0])
+	}
+}
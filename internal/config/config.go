@@ -19,6 +19,11 @@ func configDir() string {
 	return filepath.Join(home, ".intent")
 }
 
+// CacheDir returns the directory holding cached execution results
+func CacheDir() string {
+	return filepath.Join(configDir(), "cache")
+}
+
 func EnsureDir() error {
 	return os.MkdirAll(configDir(), 0o755)
 }
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+)
+
+// secretsVersion is the version of the secrets file format
+const secretsVersion = 1
+
+// secretName is the form of a secret's name, that of an input
+var secretName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// SecretStore keeps named secrets in a file, each encrypted with AES-GCM
+// under a key kept in a second file readable only by its owner. It works
+// the same on every OS, without a system keychain. Since the key sits next
+// to the secrets, the encryption only protects the secrets file when it is
+// shown or copied without the key; anyone who can read the owner's files
+// can decrypt it. Files that other users can access are refused.
+type SecretStore struct {
+	path    string
+	keyPath string
+}
+
+// secretsFile is the JSON form of a SecretStore: base64 ciphertexts by name
+type secretsFile struct {
+	Version int               `json:"version"`
+	Secrets map[string]string `json:"secrets"`
+}
+
+// SecretsDir returns the directory holding the secret store
+func SecretsDir() string {
+	return configDir()
+}
+
+// OpenSecretStore returns the store kept in dir, as secrets.json and
+// secrets.key. Both are created when the first secret is set.
+func OpenSecretStore(dir string) *SecretStore {
+	return &SecretStore{
+		path:    filepath.Join(dir, "secrets.json"),
+		keyPath: filepath.Join(dir, "secrets.key"),
+	}
+}
+
+// Get returns the secret stored under name, and whether there is one
+func (s *SecretStore) Get(name string) (string, bool, error) {
+	file, err := s.read()
+	if err != nil {
+		return "", false, err
+	}
+	sealed, ok := file.Secrets[name]
+	if !ok {
+		return "", false, nil
+	}
+	key, err := s.key(false)
+	if err != nil {
+		return "", false, err
+	}
+	value, err := open(key, name, sealed)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decrypt secret %q: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// Set stores value under name, replacing any secret of that name
+func (s *SecretStore) Set(name, value string) error {
+	if !secretName.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q (expected letters, digits, '_', '-' or '.', not starting with a digit)", name)
+	}
+	file, err := s.read()
+	if err != nil {
+		return err
+	}
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(key, name, value)
+	if err != nil {
+		return err
+	}
+	file.Secrets[name] = sealed
+	return s.write(file)
+}
+
+// Delete removes the secret stored under name, reporting whether there
+// was one
+func (s *SecretStore) Delete(name string) (bool, error) {
+	file, err := s.read()
+	if err != nil {
+		return false, err
+	}
+	if _, ok := file.Secrets[name]; !ok {
+		return false, nil
+	}
+	delete(file.Secrets, name)
+	return true, s.write(file)
+}
+
+// Names returns the names of the stored secrets, sorted
+func (s *SecretStore) Names() ([]string, error) {
+	file, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(file.Secrets))
+	for name := range file.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *SecretStore) read() (*secretsFile, error) {
+	file := &secretsFile{Version: secretsVersion, Secrets: make(map[string]string)}
+	data, err := readPrivate(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", s.path, err)
+	}
+	if file.Version != secretsVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d in %s", file.Version, s.path)
+	}
+	if file.Secrets == nil {
+		file.Secrets = make(map[string]string)
+	}
+	return file, nil
+}
+
+func (s *SecretStore) write(file *secretsFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0o600)
+}
+
+// key returns the store's encryption key, creating it when create is set
+// and there is none yet
+func (s *SecretStore) key(create bool) ([]byte, error) {
+	key, err := readPrivate(s.keyPath)
+	if errors.Is(err, fs.ErrNotExist) && create {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(s.keyPath), 0o700); err != nil {
+			return nil, err
+		}
+		return key, os.WriteFile(s.keyPath, key, 0o600)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid secrets key %s", s.keyPath)
+	}
+	return key, nil
+}
+
+// readPrivate reads a file of the store, refusing it when users other than
+// its owner have any access to it. Windows has no such mode bits, so
+// there the file is protected by the ACL of the user's profile instead.
+func readPrivate(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+		return nil, fmt.Errorf("%s can be accessed by other users (mode %04o); restrict it with chmod 600", path, perm)
+	}
+	return io.ReadAll(f)
+}
+
+// seal encrypts value, binding it to name so that entries cannot be
+// swapped, and returns the nonce and ciphertext as base64
+func seal(key []byte, name, value string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open decrypts a value sealed under name
+func open(key []byte, name, sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	value, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(name))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSecretStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".intent")
+	store := OpenSecretStore(dir)
+
+	if _, ok, err := store.Get("api_key"); ok || err != nil {
+		t.Fatalf("Expected an empty store, got %v, %v", ok, err)
+	}
+	for name, value := range map[string]string{"api_key": "s3cret", "db.password": "hunter2"} {
+		if err := store.Set(name, value); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	if err := store.Set("api_key", "rotated"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	value, ok, err := store.Get("api_key")
+	if err != nil || !ok || value != "rotated" {
+		t.Errorf("Expected the replaced secret, got %q, %v, %v", value, ok, err)
+	}
+	names, err := store.Names()
+	if err != nil || !reflect.DeepEqual(names, []string{"api_key", "db.password"}) {
+		t.Errorf("Expected both names, got %v, %v", names, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secrets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "rotated") || strings.Contains(string(data), "hunter2") {
+		t.Errorf("Expected secrets to be encrypted, got:\n%s", data)
+	}
+	for _, file := range []string{"secrets.json", "secrets.key"} {
+		if info, err := os.Stat(filepath.Join(dir, file)); err != nil || info.Mode().Perm() != 0o600 {
+			t.Errorf("Expected %s to be readable by its owner only, got %v, %v", file, info.Mode(), err)
+		}
+	}
+
+	if removed, err := store.Delete("api_key"); !removed || err != nil {
+		t.Errorf("Expected the secret to be removed, got %v, %v", removed, err)
+	}
+	if removed, _ := store.Delete("api_key"); removed {
+		t.Errorf("Expected nothing left to remove")
+	}
+	if err := store.Set("1st key", "x"); err == nil || !strings.Contains(err.Error(), "invalid secret name") {
+		t.Errorf("Expected an invalid name error, got %v", err)
+	}
+}
+
+func TestSecretStore_Tampering(t *testing.T) {
+	dir := t.TempDir()
+	store := OpenSecretStore(dir)
+	if err := store.Set("a", "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("b", "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A ciphertext moved to another name does not decrypt
+	path := filepath.Join(dir, "secrets.json")
+	var file secretsFile
+	data, _ := os.ReadFile(path)
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	file.Secrets["a"], file.Secrets["b"] = file.Secrets["b"], file.Secrets["a"]
+	data, _ = json.Marshal(file)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get("a"); err == nil || !strings.Contains(err.Error(), `failed to decrypt secret "a"`) {
+		t.Errorf("Expected a decryption error, got %v", err)
+	}
+
+	// Files other users can read are refused
+	if runtime.GOOS != "windows" {
+		for _, file := range []string{"secrets.json", "secrets.key"} {
+			path := filepath.Join(dir, file)
+			if err := os.Chmod(path, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := store.Get("b"); err == nil || !strings.Contains(err.Error(), "can be accessed by other users (mode 0644)") {
+				t.Errorf("Expected %s to be refused, got %v", file, err)
+			}
+			if err := os.Chmod(path, 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := os.Remove(filepath.Join(dir, "secrets.key")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Get("b"); err == nil || !strings.Contains(err.Error(), "failed to read secrets key") {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+}
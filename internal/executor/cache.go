@@ -0,0 +1,248 @@
+package executor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// Cache keeps the results of executions on disk, one JSON file per entry,
+// so that running an intent again with the same inputs can reuse them.
+// Only intents declaring a cache duration are cached.
+type Cache struct {
+	dir string
+}
+
+// CacheEntry is the stored result of one execution
+type CacheEntry struct {
+	Key     string        `json:"key"`
+	Intent  string        `json:"intent"`
+	Version string        `json:"version"`
+	Created time.Time     `json:"created"`
+	Expires time.Time     `json:"expires"`
+	Hits    int           `json:"hits"` // times the results were reused
+	Results ExecuteResult `json:"results"`
+}
+
+// CacheStats summarizes the entries of a cache
+type CacheStats struct {
+	Dir     string
+	Entries int
+	Expired int
+	Hits    int
+	Bytes   int64
+}
+
+// NewCache returns the cache stored in dir, which is created when the
+// first entry is stored
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// CacheKey identifies an execution: a hash of the parsed intent, version
+// and script included, and of its typed inputs with defaults applied, so
+// that inputs differing only in how they were written share an entry
+func CacheKey(intent *parser.Intent, values map[string]interface{}) (string, error) {
+	data, err := json.Marshal(struct {
+		Intent *parser.Intent         `json:"intent"`
+		Inputs map[string]interface{} `json:"inputs"`
+	}{intent, values})
+	if err != nil {
+		return "", fmt.Errorf("failed to compute cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Get returns the entry stored under key, or nil when there is none or it
+// has expired. Expired entries are removed.
+func (c *Cache) Get(key string) (*CacheEntry, error) {
+	entry, err := c.read(c.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(entry.Expires) {
+		os.Remove(c.path(key))
+		return nil, nil
+	}
+	return entry, nil
+}
+
+// Put stores results under key for ttl
+func (c *Cache) Put(key string, intent *parser.Intent, results ExecuteResult, ttl time.Duration) error {
+	now := time.Now()
+	return c.write(&CacheEntry{
+		Key:     key,
+		Intent:  intent.Name,
+		Version: intent.Version,
+		Created: now,
+		Expires: now.Add(ttl),
+		Results: results,
+	})
+}
+
+// Stats counts the entries of the cache and their size on disk. A cache
+// that was never written to is empty.
+func (c *Cache) Stats() (CacheStats, error) {
+	stats := CacheStats{Dir: c.dir}
+	files, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	now := time.Now()
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		entry, err := c.read(filepath.Join(c.dir, file.Name()))
+		if err != nil {
+			return stats, err
+		}
+		if info, err := file.Info(); err == nil {
+			stats.Bytes += info.Size()
+		}
+		stats.Entries++
+		stats.Hits += entry.Hits
+		if !now.Before(entry.Expires) {
+			stats.Expired++
+		}
+	}
+	return stats, nil
+}
+
+// hit counts a reuse of entry. Failing to count it does not matter.
+func (c *Cache) hit(entry *CacheEntry) {
+	entry.Hits++
+	_ = c.write(entry)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+func (c *Cache) read(path string) (*CacheEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var entry CacheEntry
+	if err := decoder.Decode(&entry); err != nil {
+		return nil, fmt.Errorf("invalid cache entry %s: %w", path, err)
+	}
+	for name, value := range entry.Results {
+		entry.Results[name] = cachedValue(value)
+	}
+	return &entry, nil
+}
+
+// write stores entry through a temporary file, so that concurrent runs
+// never read a partial entry
+func (c *Cache) write(entry *CacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+	tmp, err := os.CreateTemp(c.dir, entry.Key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(entry.Key))
+}
+
+// cachedValue restores a stored result value, with whole numbers as
+// int64 as scripts return them
+func cachedValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = cachedValue(elem)
+		}
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = cachedValue(elem)
+		}
+	}
+	return v
+}
+
+// cachedExecution returns the cached results of the execution and the key
+// to store new ones under, or an empty key when the execution must not be
+// cached: without a cache, a declared duration or a script, while a
+// cassette records or replays its calls, or when the workflow calls other
+// intents, whose changes the key would miss
+func cachedExecution(ctx *ExecutionContext) (ExecuteResult, string, error) {
+	if ctx.Cache == nil || ctx.Intent.Script == "" || ctx.Cassette != nil || callsIntents(ctx.Intent.Script) {
+		return nil, "", nil
+	}
+	if ttl, err := ctx.Intent.CacheTTL(); err != nil || ttl == 0 {
+		return nil, "", err
+	}
+	key, err := CacheKey(ctx.Intent, ctx.Values)
+	if err != nil || ctx.RefreshCache {
+		return nil, key, err
+	}
+	entry, err := ctx.Cache.Get(key)
+	if err != nil || entry == nil {
+		// An unreadable entry is replaced by the new results
+		return nil, key, nil
+	}
+	ctx.Cache.hit(entry)
+	return entry.Results, key, nil
+}
+
+// callsIntents reports whether a workflow script has call steps. A script
+// that does not parse is reported as not calling any, since it fails
+// before anything could be cached.
+func callsIntents(script string) bool {
+	if !strings.Contains(script, "→") {
+		return false
+	}
+	steps, err := parser.ParseWorkflow("workflow", script)
+	if err != nil {
+		return false
+	}
+	found := false
+	parser.WalkSteps(steps, func(s *parser.Step) {
+		if call, ok := s.Expr.(*parser.CallExpr); ok && s.Kind == parser.StepExpr && stepName(call) == "call" {
+			found = true
+		}
+	})
+	return found
+}
@@ -0,0 +1,167 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// countingSteps returns a registry whose tally step returns the number of
+// times it ran, failing when asked to
+func countingSteps() (*Registry, *int) {
+	calls := 0
+	registry := NewRegistry()
+	registry.Register(NewStep(StepSpec{Name: "tally", Args: []ArgSpec{{Name: "fail", Type: "boolean"}}}, func(call *StepCall) (interface{}, error) {
+		calls++
+		if call.Args[0].(bool) {
+			return nil, fmt.Errorf("service unavailable")
+		}
+		return int64(calls), nil
+	}))
+	return registry, &calls
+}
+
+func TestExecute_Cache(t *testing.T) {
+	registry, calls := countingSteps()
+	cache := NewCache(filepath.Join(t.TempDir(), "cache"))
+	intent := workflowIntent("→ n = tally(text == \"fail\")\n→ return(n=n, ratio=n / 4, items=[n, 1.5])")
+	intent.Cache = "1h"
+	run := func(inputs map[string]string, opts Options) (ExecuteResult, bool) {
+		t.Helper()
+		events := &recorder{}
+		opts.Steps, opts.Cache, opts.Observer = registry, cache, events
+		results, err := ExecuteWithOptions(intent, inputs, opts)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return results, events.events[len(events.events)-1].Cached
+	}
+
+	first, cached := run(map[string]string{"text": "a"}, Options{})
+	if cached || *calls != 1 {
+		t.Fatalf("Expected the first run to execute, got cached=%v after %d calls", cached, *calls)
+	}
+
+	// The default limit is part of the normalized inputs either way
+	second, cached := run(map[string]string{"text": "a", "limit": "2"}, Options{})
+	if !cached || *calls != 1 {
+		t.Errorf("Expected the second run to be cached, got cached=%v after %d calls", cached, *calls)
+	}
+	if !reflect.DeepEqual(second, first) {
+		t.Errorf("Expected cached results %v, got %v", first, second)
+	}
+
+	if _, cached := run(map[string]string{"text": "b"}, Options{}); cached || *calls != 2 {
+		t.Errorf("Expected other inputs to execute, got cached=%v after %d calls", cached, *calls)
+	}
+
+	refreshed, cached := run(map[string]string{"text": "a"}, Options{RefreshCache: true})
+	if cached || refreshed["n"] != int64(3) {
+		t.Errorf("Expected a refresh to execute, got cached=%v and %v", cached, refreshed)
+	}
+	if again, _ := run(map[string]string{"text": "a"}, Options{}); again["n"] != int64(3) {
+		t.Errorf("Expected a refresh to replace the cached results, got %v", again)
+	}
+
+	if _, err := ExecuteWithOptions(intent, map[string]string{"text": "fail"}, Options{Steps: registry, Cache: cache}); err == nil {
+		t.Fatal("Expected an error")
+	}
+	stats, err := cache.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	// The refreshed entry was reused once since; the failure was not stored
+	if stats.Entries != 2 || stats.Hits != 1 || stats.Expired != 0 || stats.Bytes == 0 {
+		t.Errorf("Expected 2 entries reused once, got %+v", stats)
+	}
+}
+
+func TestExecute_CacheSkipped(t *testing.T) {
+	tests := []struct {
+		name  string
+		cache string
+		opts  Options
+	}{
+		{"no duration", "", Options{}},
+		{"recording", "1h", Options{Cassette: NewCassette()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, calls := countingSteps()
+			intent := workflowIntent(`→ tally(false)`)
+			intent.Cache = tt.cache
+			tt.opts.Steps, tt.opts.Cache = registry, NewCache(t.TempDir())
+			for i := 0; i < 2; i++ {
+				if _, err := ExecuteWithOptions(intent, map[string]string{"text": "a"}, tt.opts); err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+			}
+			if *calls != 2 {
+				t.Errorf("Expected both runs to execute, got %d calls", *calls)
+			}
+		})
+	}
+}
+
+func TestCache_Expiry(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir)
+	intent := workflowIntent(`→ upper(text)`)
+	if err := cache.Put("old", intent, ExecuteResult{"result": "A"}, -time.Second); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 1 || stats.Expired != 1 {
+		t.Errorf("Expected 1 expired entry, got %+v", stats)
+	}
+	entry, err := cache.Get("old")
+	if err != nil || entry != nil {
+		t.Errorf("Expected no entry, got %+v, %v", entry, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the expired entry to be removed, got %v", err)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	intent := workflowIntent(`→ upper(text)`)
+	key := mustKey(t, intent, map[string]interface{}{"text": "a", "limit": int64(2)})
+	if same := mustKey(t, workflowIntent(`→ upper(text)`), map[string]interface{}{"limit": int64(2), "text": "a"}); same != key {
+		t.Errorf("Expected the same key for the same intent and inputs")
+	}
+
+	changed := workflowIntent(`→ upper(text)`)
+	changed.Version = "2.0.0"
+	for _, other := range []string{
+		mustKey(t, changed, map[string]interface{}{"text": "a", "limit": int64(2)}),
+		mustKey(t, workflowIntent(`→ lower(text)`), map[string]interface{}{"text": "a", "limit": int64(2)}),
+		mustKey(t, intent, map[string]interface{}{"text": "b", "limit": int64(2)}),
+	} {
+		if other == key {
+			t.Errorf("Expected a different key when the version, script or inputs change")
+		}
+	}
+}
+
+func mustKey(t *testing.T, intent *parser.Intent, values map[string]interface{}) string {
+	t.Helper()
+	key, err := CacheKey(intent, values)
+	if err != nil {
+		t.Fatalf("CacheKey failed: %v", err)
+	}
+	return key
+}
+
+func TestExecute_InvalidCacheDuration(t *testing.T) {
+	intent := workflowIntent(`→ upper(text)`)
+	intent.Cache = "soon"
+	_, err := ExecuteWithOptions(intent, map[string]string{"text": "a"}, Options{Cache: NewCache(t.TempDir())})
+	if err == nil || !strings.Contains(err.Error(), `invalid cache duration "soon"`) {
+		t.Errorf("Expected an invalid duration error, got %v", err)
+	}
+}
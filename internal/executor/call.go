@@ -0,0 +1,210 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// packagesDir is the directory `intent install` puts packages in
+const packagesDir = "intents"
+
+// callStep runs the intent named by its first argument with the inputs in
+// its second, and returns the callee's results. The callee is checked and
+// run like an intent given to `intent run`, with the policies of its own
+// package, or those of the caller when it has none.
+func callStep(call *StepCall) (interface{}, error) {
+	caller := call.exec
+	target := call.Args[0].(string)
+	path, err := resolveIntent(target, caller.Intent.Path)
+	if err != nil {
+		return nil, err
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// A callee already running further up would call itself forever
+	chain := caller.callers
+	if caller.Intent.Path != "" {
+		self, err := filepath.Abs(caller.Intent.Path)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(append([]string(nil), chain...), self)
+	}
+	for i, p := range chain {
+		if p == path {
+			cycle := make([]string, 0, len(chain)-i+1)
+			for _, p := range append(chain[i:], path) {
+				cycle = append(cycle, displayPath(p))
+			}
+			return nil, fmt.Errorf("call cycle: %s", strings.Join(cycle, " → "))
+		}
+	}
+
+	intent, err := parser.ParseITML(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", target, err)
+	}
+
+	// Inputs are passed as they would be on the command line
+	inputs := make(map[string]string)
+	if len(call.Args) > 1 {
+		for name, value := range call.Args[1].(map[string]interface{}) {
+			if value != nil {
+				inputs[name] = formatValue(value)
+			}
+		}
+	}
+	if err := ValidateInputs(intent, inputs); err != nil {
+		return nil, fmt.Errorf("invalid inputs for %s: %w", target, err)
+	}
+
+	opts := Options{
+		Network:      caller.Network,
+		Capabilities: caller.Capabilities,
+		PackageDir:   caller.PackageDir,
+		Steps:        caller.Steps,
+		Scripts:      caller.Scripts,
+		Mock:         caller.Mock,
+		Cassette:     caller.Cassette,
+		Cache:        caller.Cache,
+		RefreshCache: caller.RefreshCache,
+	}
+	manifest, manifestPath, err := pack.FindManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil {
+		if opts.Network, err = manifest.NetworkPolicy(); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifestPath, err)
+		}
+		if opts.Scripts, err = manifest.ScriptPolicy(); err != nil {
+			return nil, fmt.Errorf("%s: %w", manifestPath, err)
+		}
+		opts.Capabilities = append([]string{}, manifest.Capabilities...)
+		opts.PackageDir = filepath.Dir(manifestPath)
+	}
+
+	results, err := executeCall(call, intent, inputs, opts, chain)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", target, err)
+	}
+	return map[string]interface{}(results), nil
+}
+
+// executeCall runs a callee as ExecuteContext does, remembering the chain
+// of callers so that cycles are found however deep they are
+func executeCall(call *StepCall, intent *parser.Intent, inputs map[string]string, opts Options, chain []string) (ExecuteResult, error) {
+	values, err := intent.ResolveParameters(inputs)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &ExecutionContext{
+		Context:      call.Context,
+		Intent:       intent,
+		Inputs:       inputs,
+		Values:       values,
+		Network:      opts.Network,
+		Capabilities: opts.Capabilities,
+		PackageDir:   opts.PackageDir,
+		Steps:        opts.Steps,
+		Scripts:      opts.Scripts,
+		Mock:         opts.Mock,
+		Observer:     call.exec.Observer,
+		Cassette:     opts.Cassette,
+		Cache:        opts.Cache,
+		RefreshCache: opts.RefreshCache,
+		Results:      make(ExecuteResult),
+		span:         call.exec.newSpan(),
+		caller:       call.exec,
+		callers:      chain,
+		secrets:      newSecretValues(intent, values, call.exec.secrets),
+	}
+	// The callee's events nest under the call step
+	return run(ctx, call.span)
+}
+
+// resolveIntent returns the file a call targets. "./x.itml" and other paths
+// are relative to the calling intent's directory. "@scope/name" is the
+// entry intent of a package installed in an intents/ directory next to the
+// caller or in one of its parents; "@scope/name@1.2.0" also requires the
+// installed version to match.
+func resolveIntent(target, from string) (string, error) {
+	dir := "."
+	if from != "" {
+		dir = filepath.Dir(from)
+	}
+	if !strings.HasPrefix(target, "@") {
+		if !strings.HasSuffix(target, ".itml") {
+			return "", fmt.Errorf("cannot call %q: expected a .itml file or an @scope/name package", target)
+		}
+		if filepath.IsAbs(target) {
+			return target, nil
+		}
+		return filepath.Join(dir, target), nil
+	}
+
+	name, version := target, ""
+	if i := strings.LastIndex(target, "@"); i > 0 {
+		name, version = target[:i], target[i+1:]
+	}
+	if !strings.Contains(name, "/") {
+		return "", fmt.Errorf("cannot call %q: expected a .itml file or an @scope/name package", target)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		pkgDir := pack.PackageDir(filepath.Join(dir, packagesDir), name)
+		if _, err := os.Stat(pkgDir); err == nil {
+			return packageEntry(pkgDir, name, version)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("package %s is not installed (run 'intent install %s')", name, target)
+		}
+		dir = parent
+	}
+}
+
+// packageEntry returns the entry intent of the package installed in dir,
+// which must be inside dir
+func packageEntry(dir, name, version string) (string, error) {
+	manifest, err := pack.ReadItpkgManifest(filepath.Join(dir, "itpkg.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the manifest of %s: %w", name, err)
+	}
+	if version != "" && manifest.Version != version {
+		return "", fmt.Errorf("package %s is installed at version %s, not %s", name, manifest.Version, version)
+	}
+	if manifest.Entry == "" {
+		return "", fmt.Errorf("package %s has no entry intent to call", name)
+	}
+	entry := filepath.Join(dir, manifest.Entry)
+	rel, err := filepath.Rel(dir, entry)
+	if filepath.IsAbs(manifest.Entry) || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("package %s has an entry outside its directory: %s", name, manifest.Entry)
+	}
+	return entry, nil
+}
+
+// displayPath shortens path relative to the working directory, for messages
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
@@ -0,0 +1,232 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// writeFiles creates files, given by slash-separated path, under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// callProject is a project whose app/ intents call each other and an
+// installed package
+var callProject = map[string]string{
+	"app/helper.itml": `intent "Helper"
+inputs:
+  - text (string) required
+  - times (integer) default=1
+  - tags (array) default=[]
+outputs:
+  - shout (string)
+  - length (integer)
+workflow:
+  → return(shout=upper(text), length=length(text) * times + length(tags))
+`,
+	"intents/@acme-greet/itpkg.json": `{"name": "@acme/greet", "version": "1.0.0", "entry": "greet.itml", "capabilities": []}`,
+	"intents/@acme-greet/greet.itml": `intent "Greet"
+inputs:
+  - who (string) required
+outputs:
+  - greeting (string)
+workflow:
+  → return(greeting="Hello, {who}!")
+`,
+	"intents/@acme-fetch/itpkg.json": `{"name": "@acme/fetch", "version": "0.1.0", "entry": "fetch.itml", "capabilities": []}`,
+	"intents/@acme-fetch/fetch.itml": `intent "Fetch"
+workflow:
+  → http.get("http://127.0.0.1:1/")
+`,
+	"intents/@acme-lib/itpkg.json": `{"name": "@acme/lib", "version": "1.0.0", "type": "lib"}`,
+	"intents/@acme-up/itpkg.json":  `{"name": "@acme/up", "version": "1.0.0", "entry": "../../app/helper.itml"}`,
+	"intents/@acme-abs/itpkg.json": `{"name": "@acme/abs", "version": "1.0.0", "entry": "/etc/passwd"}`,
+	"app/loop.itml":                "intent \"Loop\"\nworkflow:\n  → call(\"./loop2.itml\")\n",
+	"app/loop2.itml":               "intent \"Loop2\"\nworkflow:\n  → call(\"./loop.itml\")\n",
+	"app/self.itml":                "intent \"Self\"\nworkflow:\n  → call(\"self.itml\")\n",
+}
+
+func TestExecute_Call(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, callProject)
+	writeFiles(t, dir, map[string]string{"app/main.itml": `intent "Main"
+inputs:
+  - name (string) required
+workflow:
+  → up = call("./helper.itml", { text: name, times: 2, tags: ["a"] })
+  → greeting = call("@acme/greet@1.0.0", { who: up.shout })
+  → return(message=greeting.greeting, count=up.length, helper=up.status)
+`})
+	intent, err := parser.ParseITML(filepath.Join(dir, "app", "main.itml"))
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	results, err := Execute(intent, map[string]string{"name": "ann"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := ExecuteResult{"message": "Hello, ANN!", "count": int64(7), "helper": "success", "status": "success"}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected %v, got %v", want, results)
+	}
+}
+
+func TestExecute_CallEvents(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, callProject)
+	writeFiles(t, dir, map[string]string{"app/main.itml": `intent "Main"
+workflow:
+  → up = call("./helper.itml", { text: "a" })
+  → return(shout=up.shout)
+`})
+	intent, err := parser.ParseITML(filepath.Join(dir, "app", "main.itml"))
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	events := &recorder{}
+	if _, err := ExecuteWithOptions(intent, nil, Options{Observer: events}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	var got []string
+	spans := make(map[int64]bool)
+	for _, e := range events.events {
+		got = append(got, fmt.Sprintf("%s %s %d<-%d", e.Type, e.Intent, e.Span, e.Parent))
+		if e.Type == EventExecutionStart || e.Type == EventStepStart {
+			if spans[e.Span] {
+				t.Errorf("Expected span %d to be started once", e.Span)
+			}
+			spans[e.Span] = true
+		}
+	}
+	// The helper's execution and its step nest under the call step
+	want := []string{
+		"execution.start Main 1<-0",
+		"step.start Main 2<-1",
+		"execution.start Helper 3<-2",
+		"step.start Helper 4<-3",
+		"step.end Helper 4<-3",
+		"execution.end Helper 3<-2",
+		"step.end Main 2<-1",
+		"step.start Main 5<-1",
+		"step.end Main 5<-1",
+		"execution.end Main 1<-0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected events\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestExecute_CallNotCached(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, callProject)
+	writeFiles(t, dir, map[string]string{"app/main.itml": `intent "Main"
+cache: "1h"
+workflow:
+  → up = call("./helper.itml", { text: "a" })
+  → return(shout=up.shout)
+`})
+	cache := NewCache(filepath.Join(t.TempDir(), "cache"))
+	run := func() interface{} {
+		t.Helper()
+		intent, err := parser.ParseITML(filepath.Join(dir, "app", "main.itml"))
+		if err != nil {
+			t.Fatalf("ParseITML failed: %v", err)
+		}
+		results, err := ExecuteWithOptions(intent, nil, Options{Cache: cache})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		return results["shout"]
+	}
+
+	if got := run(); got != "A" {
+		t.Fatalf("Expected A, got %v", got)
+	}
+	// The caller must see its callee change
+	writeFiles(t, dir, map[string]string{"app/helper.itml": strings.Replace(callProject["app/helper.itml"], "upper(text)", `text + "!"`, 1)})
+	if got := run(); got != "a!" {
+		t.Errorf("Expected the changed callee's result a!, got %v", got)
+	}
+	if stats, _ := cache.Stats(); stats.Entries != 0 {
+		t.Errorf("Expected nothing to be cached, got %d entries", stats.Entries)
+	}
+}
+
+func TestExecute_CallErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, callProject)
+	tests := []struct {
+		name string
+		file string
+		step string
+		want string
+	}{
+		{"cycle", "loop.itml", "", "call cycle: "},
+		{"self", "self.itml", "", "call cycle: "},
+		{"unknown input", "bad.itml", `call("./helper.itml", { text: "a", colour: "red" })`, "invalid inputs for ./helper.itml: invalid value for parameter 'colour': unknown parameter"},
+		{"missing input", "bad.itml", `call("./helper.itml", {})`, "'text': required parameter not provided"},
+		{"invalid input", "bad.itml", `call("./helper.itml", { text: "a", times: "many" })`, "invalid integer"},
+		{"missing file", "bad.itml", `call("./nothing.itml")`, "failed to load ./nothing.itml"},
+		{"not an intent", "bad.itml", `call("helper")`, `cannot call "helper": expected a .itml file or an @scope/name package`},
+		{"not installed", "bad.itml", `call("@acme/other")`, "package @acme/other is not installed"},
+		{"version", "bad.itml", `call("@acme/greet@2.0.0", { who: "x" })`, "package @acme/greet is installed at version 1.0.0, not 2.0.0"},
+		{"no entry", "bad.itml", `call("@acme/lib")`, "package @acme/lib has no entry intent to call"},
+		{"entry outside package", "bad.itml", `call("@acme/up", { text: "a" })`, "package @acme/up has an entry outside its directory: ../../app/helper.itml"},
+		{"absolute entry", "bad.itml", `call("@acme/abs")`, "package @acme/abs has an entry outside its directory: /etc/passwd"},
+		{"callee policy", "bad.itml", `call("@acme/fetch")`, "step http.get requires http.outbound, which the package does not declare"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.step != "" {
+				writeFiles(t, dir, map[string]string{"app/" + tt.file: "intent \"Bad\"\nworkflow:\n  → " + tt.step + "\n"})
+			}
+			intent, err := parser.ParseITML(filepath.Join(dir, "app", tt.file))
+			if err != nil {
+				t.Fatalf("ParseITML failed: %v", err)
+			}
+			_, err = Execute(intent, nil, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestResolveIntent(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, callProject)
+	from := filepath.Join(dir, "app", "nested", "caller.itml")
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"./helper.itml", filepath.Join(dir, "app", "nested", "helper.itml")},
+		{"../helper.itml", filepath.Join(dir, "app", "helper.itml")},
+		{"@acme/greet", filepath.Join(dir, "intents", "@acme-greet", "greet.itml")},
+		{"@acme/greet@1.0.0", filepath.Join(dir, "intents", "@acme-greet", "greet.itml")},
+	}
+	for _, tt := range tests {
+		got, err := resolveIntent(tt.target, from)
+		if err != nil {
+			t.Errorf("resolveIntent(%q) failed: %v", tt.target, err)
+		} else if got != tt.want {
+			t.Errorf("resolveIntent(%q): expected %s, got %s", tt.target, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,328 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// cassetteVersion is the version of the cassette file format
+const cassetteVersion = 1
+
+// cassetteFile is the JSON form of a cassette
+type cassetteFile struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Kinds of Interaction
+const (
+	InteractionHTTP   = "http"
+	InteractionPlugin = "plugin"
+)
+
+// Cassette records the external calls of an execution, HTTP requests and
+// plugin steps, or replays recorded ones without making them. A nil
+// *Cassette makes every call for real. Secrets are masked in what it
+// records, and in the calls it matches against recordings, so that
+// cassettes can be shared.
+type Cassette struct {
+	mu           sync.Mutex
+	replay       bool
+	used         []bool
+	interactions []*Interaction
+	secrets      secretValues
+}
+
+// Interaction is one recorded call and its outcome
+type Interaction struct {
+	Kind     string              `json:"kind"`
+	Request  InteractionRequest  `json:"request"`
+	Response InteractionResponse `json:"response"`
+}
+
+// InteractionRequest identifies a call. HTTP requests are matched by
+// method, URL and body; plugin calls by step and arguments, wherever the
+// plugin binary is installed. Request headers are not recorded, so that
+// credentials stay out of cassettes, and secrets are masked elsewhere.
+type InteractionRequest struct {
+	Method string        `json:"method,omitempty"`
+	URL    string        `json:"url,omitempty"`
+	Body   string        `json:"body,omitempty"`
+	Plugin string        `json:"plugin,omitempty"`
+	Step   string        `json:"step,omitempty"`
+	Args   []interface{} `json:"args,omitempty"`
+}
+
+// InteractionResponse is the outcome of a call: an HTTP response, the
+// JSON response of a plugin, or the error the call failed with
+type InteractionResponse struct {
+	Status   int               `json:"status,omitempty"`
+	Headers  map[string]string `json:"headers,omitempty"`
+	Body     string            `json:"body,omitempty"`
+	Encoding string            `json:"encoding,omitempty"` // "base64" for bodies that are not text
+	Error    string            `json:"error,omitempty"`
+}
+
+// CassetteMismatchError reports a call a replayed cassette has no
+// recorded interaction for
+type CassetteMismatchError struct {
+	Request InteractionRequest
+}
+
+func (e *CassetteMismatchError) Error() string {
+	if e.Request.Step != "" {
+		return fmt.Sprintf("cassette has no recorded call of %s with these arguments", e.Request.Step)
+	}
+	return fmt.Sprintf("cassette has no recorded response for %s %s", e.Request.Method, e.Request.URL)
+}
+
+// NewCassette returns an empty cassette that records calls as they are made
+func NewCassette() *Cassette {
+	return &Cassette{}
+}
+
+// LoadCassette reads a cassette saved by Save, to replay its interactions
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file cassetteFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	if file.Version != cassetteVersion {
+		return nil, fmt.Errorf("unsupported cassette version %d in %s", file.Version, path)
+	}
+	return &Cassette{replay: true, interactions: file.Interactions, used: make([]bool, len(file.Interactions))}, nil
+}
+
+// Interactions returns the interactions recorded or loaded so far
+func (c *Cassette) Interactions() []*Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*Interaction(nil), c.interactions...)
+}
+
+// Save writes the cassette's interactions to path as JSON
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	interactions := c.interactions
+	if interactions == nil {
+		interactions = []*Interaction{}
+	}
+	data, err := json.MarshalIndent(cassetteFile{Version: cassetteVersion, Interactions: interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// hide masks secrets in the calls recorded or replayed from now on. It
+// does nothing when c is nil.
+func (c *Cassette) hide(secrets secretValues) {
+	if c == nil || len(secrets) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.secrets = append(append(secretValues(nil), c.secrets...), secrets...)
+	sort.SliceStable(c.secrets, func(i, j int) bool { return len(c.secrets[i]) > len(c.secrets[j]) })
+}
+
+// masked returns the secrets to mask
+func (c *Cassette) masked() secretValues {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.secrets
+}
+
+// Transport returns the HTTP transport to send requests with: one going
+// through the cassette, or nil for the default transport when c is nil.
+// While recording, a response body over limit bytes fails the request
+// instead of being read into memory.
+func (c *Cassette) Transport(limit int64) http.RoundTripper {
+	if c == nil {
+		return nil
+	}
+	return &cassetteTransport{cassette: c, limit: limit}
+}
+
+// cassetteTransport sends HTTP requests through a cassette
+type cassetteTransport struct {
+	cassette *Cassette
+	limit    int64 // largest response body recorded, in bytes
+}
+
+// RoundTrip records or replays one HTTP request
+func (t *cassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.cassette
+	secrets := c.masked()
+	key := InteractionRequest{Method: req.Method, URL: secrets.redact(req.URL.String())}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		key.Body = secrets.redact(string(body))
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if c.replay {
+		recorded, err := c.find(InteractionHTTP, key)
+		if err != nil {
+			return nil, err
+		}
+		return recorded.httpResponse(req)
+	}
+
+	interaction := &Interaction{Kind: InteractionHTTP, Request: key}
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		interaction.Response.Error = secrets.redact(err.Error())
+		c.add(interaction)
+		return nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.limit+1))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > t.limit {
+		return nil, &pack.PolicyViolation{
+			Policy: "network.outbound.maxBodySize",
+			Reason: fmt.Sprintf("response from %s exceeds the limit of %d bytes", req.URL.Host, t.limit),
+		}
+	}
+	interaction.Response.Status = resp.StatusCode
+	interaction.Response.Headers = make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		interaction.Response.Headers[name] = secrets.redact(resp.Header.Get(name))
+	}
+	interaction.Response.setBody(body, secrets)
+	c.add(interaction)
+
+	// Only the recording is masked; the execution gets the real response
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// plugin records or replays one call of a plugin step, made by run
+func (c *Cassette) plugin(command string, req *pluginRequest, run func() (*pluginResponse, error)) (*pluginResponse, error) {
+	if c == nil {
+		return run()
+	}
+	secrets := c.masked()
+	key := InteractionRequest{Plugin: command, Step: req.Step, Args: req.Args}
+	if len(secrets) > 0 {
+		key.Args = secrets.redactValue(req.Args).([]interface{})
+	}
+
+	if c.replay {
+		recorded, err := c.find(InteractionPlugin, key)
+		if err != nil {
+			return nil, err
+		}
+		if recorded.Response.Error != "" {
+			return nil, errors.New(recorded.Response.Error)
+		}
+		var resp pluginResponse
+		if err := json.Unmarshal([]byte(recorded.Response.Body), &resp); err != nil {
+			return nil, fmt.Errorf("invalid recorded response of %s: %w", req.Step, err)
+		}
+		return &resp, nil
+	}
+
+	interaction := &Interaction{Kind: InteractionPlugin, Request: key}
+	resp, err := run()
+	if err != nil {
+		interaction.Response.Error = secrets.redact(err.Error())
+	} else {
+		data, _ := json.Marshal(resp)
+		interaction.Response.Body = secrets.redact(string(data))
+	}
+	c.add(interaction)
+	return resp, err
+}
+
+func (c *Cassette) add(interaction *Interaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, interaction)
+}
+
+// find returns the first unused recorded interaction matching key, so
+// that repeated calls get their responses in the order they were recorded
+func (c *Cassette) find(kind string, key InteractionRequest) (*Interaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	want := canonicalRequest(key)
+	for i, recorded := range c.interactions {
+		if !c.used[i] && recorded.Kind == kind && canonicalRequest(recorded.Request) == want {
+			c.used[i] = true
+			return recorded, nil
+		}
+	}
+	return nil, &CassetteMismatchError{Request: key}
+}
+
+// canonicalRequest encodes a request for comparison. Arguments are
+// compared as JSON, so that int64(1) matches a recorded 1.
+func canonicalRequest(r InteractionRequest) string {
+	r.Plugin = ""
+	data, _ := json.Marshal(r)
+	return string(data)
+}
+
+// setBody stores a response body, as base64 unless it is text, which has
+// secrets masked
+func (r *InteractionResponse) setBody(body []byte, secrets secretValues) {
+	if utf8.Valid(body) {
+		r.Body = secrets.redact(string(body))
+		return
+	}
+	r.Body, r.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+}
+
+// httpResponse rebuilds the recorded HTTP response to req
+func (i *Interaction) httpResponse(req *http.Request) (*http.Response, error) {
+	if i.Response.Error != "" {
+		return nil, errors.New(i.Response.Error)
+	}
+	body := []byte(i.Response.Body)
+	if i.Response.Encoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(i.Response.Body); err != nil {
+			return nil, fmt.Errorf("invalid recorded body for %s %s: %w", req.Method, req.URL, err)
+		}
+	}
+	header := make(http.Header, len(i.Response.Headers))
+	for name, value := range i.Response.Headers {
+		header.Set(name, value)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", i.Response.Status, http.StatusText(i.Response.Status)),
+		StatusCode:    i.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
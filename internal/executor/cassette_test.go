@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+func TestCassette_RecordAndReplayHTTP(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Method == http.MethodPost {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0x00, byte(hits)})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"hits": ` + string(rune('0'+hits)) + `}`))
+	}))
+	defer server.Close()
+
+	script := `→ first = http.get("` + server.URL + `/count")
+→ second = http.get("` + server.URL + `/count")
+→ blob = http.post("` + server.URL + `/upload", { name: text })
+→ return(first=first.hits, second=second.hits, blob=length(blob))`
+	intent := workflowIntent(script)
+	inputs := map[string]string{"text": "x"}
+
+	recording := NewCassette()
+	recorded, err := ExecuteWithOptions(intent, inputs, Options{Cassette: recording})
+	if err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recording.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	server.Close()
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette failed: %v", err)
+	}
+	replayed, err := ExecuteWithOptions(intent, inputs, Options{Cassette: cassette})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if hits != 3 {
+		t.Errorf("Expected 3 requests to the server, got %d", hits)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Expected replayed results %v, got %v", recorded, replayed)
+	}
+	if fmt.Sprint(replayed["first"], replayed["second"]) != "1 2" {
+		t.Errorf("Expected repeated requests to replay in order, got %v", replayed)
+	}
+
+	interactions := cassette.Interactions()
+	if len(interactions) != 3 || interactions[2].Request.Body != `{"name":"x"}` || interactions[2].Response.Encoding != "base64" {
+		t.Errorf("Unexpected interactions: %+v", interactions)
+	}
+}
+
+func TestCassette_RecordLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	policy := policyFor(t, server.URL)
+	policy.MaxBodySize = 10
+	recording := NewCassette()
+	_, err := ExecuteWithOptions(workflowIntent(`→ http.get("`+server.URL+`")`), map[string]string{"text": "x"}, Options{Network: policy, Cassette: recording})
+	var violation *pack.PolicyViolation
+	if !errors.As(err, &violation) || !strings.Contains(err.Error(), "exceeds the limit of 10 bytes") {
+		t.Errorf("Expected a maxBodySize violation, got %v", err)
+	}
+	if interactions := recording.Interactions(); len(interactions) != 0 {
+		t.Errorf("Expected nothing to be recorded, got %+v", interactions)
+	}
+}
+
+func TestCassette_RecordUnmaskedResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Token", r.URL.Query().Get("key"))
+		w.Write([]byte(r.URL.Query().Get("key")))
+	}))
+	defer server.Close()
+
+	// The secret is masked in the cassette but not in what the run reads
+	intent := secretIntent(`→ data = http.get("` + server.URL + `?key={token}")
+→ return(length=length(data))`)
+	recording := NewCassette()
+	results, err := ExecuteWithOptions(intent, map[string]string{"text": "x", "token": testSecret}, Options{Cassette: recording})
+	if err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+	if results["length"] != int64(len(testSecret)) {
+		t.Errorf("Expected the live response to hold the secret, got %v", results)
+	}
+	interactions := recording.Interactions()
+	if len(interactions) != 1 || interactions[0].Response.Body != secretMask || interactions[0].Response.Headers["X-Token"] != secretMask {
+		t.Errorf("Expected the recorded response to be masked, got %+v", interactions)
+	}
+}
+
+func TestCassette_Mismatch(t *testing.T) {
+	cassette := NewCassette()
+	path := filepath.Join(t.TempDir(), "empty.json")
+	if err := cassette.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := &recorder{}
+	_, err = ExecuteWithOptions(workflowIntent(`→ http.get("http://example.com/{text}") retry=2 backoff="1ms"`), map[string]string{"text": "x"}, Options{Cassette: cassette, Observer: events})
+	var mismatch *CassetteMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected *CassetteMismatchError, got %v", err)
+	}
+	if want := "cassette has no recorded response for GET http://example.com/x"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+	if end := events.events[len(events.events)-2]; end.Attempts != 1 {
+		t.Errorf("Expected a mismatch not to be retried, got %d attempts", end.Attempts)
+	}
+}
+
+func TestCassette_RecordAndReplayPlugin(t *testing.T) {
+	t.Setenv("INTENT_TEST_PLUGIN", "1")
+	registry := NewRegistry()
+	if _, err := registry.LoadPlugin(os.Args[0], "-test.run=^TestPluginProcess$"); err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	intent := workflowIntent("→ a = kv.get(\"colour\")\n→ b = kv.get(\"missing\") on_error=continue\n→ return(a=a)")
+	inputs := map[string]string{"text": "ann"}
+
+	recording := NewCassette()
+	recorded, err := ExecuteWithOptions(intent, inputs, Options{Steps: registry, Cassette: recording})
+	if err != nil {
+		t.Fatalf("Recording failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recording.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plugin that would fail shows that the replay does not run it
+	t.Setenv("INTENT_TEST_PLUGIN", "0")
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ExecuteWithOptions(intent, inputs, Options{Steps: registry, Cassette: cassette})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, recorded) {
+		t.Errorf("Expected replayed results %v, got %v", recorded, replayed)
+	}
+
+	_, err = ExecuteWithOptions(workflowIntent(`→ kv.get("size")`), inputs, Options{Steps: registry, Cassette: cassette})
+	if err == nil || !strings.Contains(err.Error(), "cassette has no recorded call of kv.get") {
+		t.Errorf("Expected a mismatch, got %v", err)
+	}
+}
+
+func TestLoadCassette_Errors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"invalid JSON", `{`, "invalid cassette"},
+		{"version", `{"version": 9, "interactions": []}`, "unsupported cassette version 9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadCassette(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// CheckWorkflow checks workflow steps before they run. Every function and
+// step must exist and get the right number of arguments, arguments must
+// have kinds it accepts, and steps must only need capabilities granted by
+// opts. Kinds come from the declared input types and from earlier steps;
+// values returned under a declared output's name must match its type. All
+// problems are reported together as parser.Diagnostics.
+func CheckWorkflow(intent *parser.Intent, steps []*parser.Step, opts Options) error {
+	_, err := checkWorkflow(intent, steps, opts)
+	return err
+}
+
+// checkWorkflow checks steps as CheckWorkflow does, and returns the kind
+// inferred for the result of each step
+func checkWorkflow(intent *parser.Intent, steps []*parser.Step, opts Options) (map[*parser.Step]string, error) {
+	c := &checker{
+		vars:         make(map[string]string),
+		outputs:      make(map[string]string),
+		registry:     opts.Steps,
+		capabilities: opts.Capabilities,
+		stepKinds:    make(map[*parser.Step]string),
+	}
+	if c.registry == nil {
+		c.registry = DefaultRegistry
+	}
+	for _, param := range intent.Parameters {
+		c.vars[param.Name] = typeKind(param.Type)
+	}
+	for _, output := range intent.Outputs {
+		c.outputs[output.Name] = typeKind(output.Type)
+	}
+	c.vars["input"] = "object"
+	c.vars["steps"] = "object"
+	c.vars["result"] = "null"
+
+	c.block(steps)
+	return c.stepKinds, c.diags.Err()
+}
+
+// typeKind maps an ITML type to the kind of value it holds at run time
+func typeKind(t string) string {
+	switch t {
+	case "string", "text", "url", "file", "secret":
+		return "string"
+	case "integer", "boolean", "array", "object":
+		return t
+	case "number", "float":
+		return "number"
+	}
+	return "any"
+}
+
+// accepts reports whether a value of kind got fits the kinds in want.
+// Integers and numbers are interchangeable, and null or unknown values
+// are left to be checked at run time.
+func accepts(want, got string) bool {
+	if got == "any" || got == "null" {
+		return true
+	}
+	for _, alt := range strings.Split(want, "|") {
+		if alt == "any" || alt == got || (isNumeric(alt) && isNumeric(got)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(kind string) bool {
+	return kind == "integer" || kind == "number"
+}
+
+// known reports whether kind is precise enough to reject an operation
+func known(kind string) bool {
+	return kind != "any" && kind != "null"
+}
+
+// checker infers the kind of each expression, recording problems as it goes
+type checker struct {
+	vars         map[string]string
+	outputs      map[string]string
+	registry     *Registry
+	capabilities []string
+	stepKinds    map[*parser.Step]string
+	diags        parser.Diagnostics
+}
+
+func (c *checker) errorf(node parser.Expr, format string, args ...interface{}) {
+	c.diags = append(c.diags, &parser.Diagnostic{
+		Pos: node.Pos(),
+		Msg: node.String() + ": " + fmt.Sprintf(format, args...),
+	})
+}
+
+// block checks steps in order, tracking the kinds of named results.
+// Names bound inside a branch stay visible after it, as at run time.
+func (c *checker) block(steps []*parser.Step) {
+	for _, step := range steps {
+		kind := c.step(step)
+		c.stepKinds[step] = kind
+		if step.Name != "" {
+			c.vars[step.Name] = kind
+		}
+		c.vars["result"] = kind
+	}
+}
+
+// step checks one workflow step and returns the kind of its result
+func (c *checker) step(step *parser.Step) string {
+	switch step.Kind {
+	case parser.StepIf:
+		c.kind(step.Expr)
+		before := c.vars["result"]
+		c.block(step.Body)
+		taken := c.vars["result"]
+		c.vars["result"] = before
+		c.block(step.Else)
+		if c.vars["result"] != taken {
+			return "any"
+		}
+		return taken
+	case parser.StepFor:
+		if kind := c.kind(step.Expr); !accepts("array", kind) {
+			c.errorf(step.Expr, "for loop expects an array, got %s", kind)
+		}
+		c.vars[step.Var] = "any"
+		c.block(step.Body)
+		delete(c.vars, step.Var)
+		return "array"
+	case parser.StepTry:
+		c.block(step.Body)
+		tried := c.vars["result"]
+		if step.Var != "" {
+			c.vars[step.Var] = "object"
+		}
+		c.block(step.Else)
+		delete(c.vars, step.Var)
+		if c.vars["result"] != tried {
+			return "any"
+		}
+		return tried
+	case parser.StepParallel:
+		c.parallel(step)
+		return "object"
+	}
+
+	kind := c.plain(step)
+	switch step.Options.OnError {
+	case parser.OnErrorContinue:
+		return "any"
+	case parser.OnErrorFallback:
+		if c.kind(step.Options.Fallback) != kind {
+			return "any"
+		}
+	}
+	return kind
+}
+
+// parallel checks the branches of a parallel step. Each branch only sees
+// the names bound before the step; the names bound in any branch are
+// visible after it. A branch cannot return, since the others are still
+// running.
+func (c *checker) parallel(step *parser.Step) {
+	before := c.vars
+	bound := make(map[string]string)
+	for _, branch := range step.Body {
+		c.vars = make(map[string]string, len(before))
+		for name, kind := range before {
+			c.vars[name] = kind
+		}
+		c.block([]*parser.Step{branch})
+		parser.WalkSteps([]*parser.Step{branch}, func(s *parser.Step) {
+			if call, ok := s.Expr.(*parser.CallExpr); ok && s.Kind == parser.StepExpr && stepName(call) == "return" {
+				c.errorf(call, "return cannot be used inside parallel")
+			}
+			if s.Name != "" {
+				bound[s.Name] = c.vars[s.Name]
+			}
+		})
+	}
+	c.vars = before
+	for name, kind := range bound {
+		c.vars[name] = kind
+	}
+}
+
+// plain checks a step that is not a block and returns the kind of its result
+func (c *checker) plain(step *parser.Step) string {
+	if call, ok := step.Expr.(*parser.CallExpr); ok {
+		name := stepName(call)
+		if name == "return" {
+			c.checkReturn(call)
+			return "null"
+		}
+		if h, ok := c.registry.Lookup(name); ok {
+			spec := h.Spec()
+			if missing := missingCapabilities(spec, c.capabilities); len(missing) > 0 {
+				c.errorf(call, "step %s requires %s, which the package does not declare", name, strings.Join(missing, ", "))
+			}
+			return c.checkArgs(call, name, stepFunction(spec), c.kinds(call.Args))
+		}
+	}
+	return c.kind(step.Expr)
+}
+
+// checkReturn matches returned values against the declared outputs
+func (c *checker) checkReturn(call *parser.CallExpr) {
+	for _, arg := range call.Args {
+		kind := c.kind(arg.Value)
+		want, declared := c.outputs[arg.Name]
+		if arg.Name != "" && declared && !accepts(want, kind) {
+			c.errorf(arg.Value, "output '%s' is declared %s, got %s", arg.Name, want, kind)
+		}
+	}
+}
+
+func (c *checker) kinds(args []*parser.Arg) []string {
+	kinds := make([]string, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			c.errorf(a.Value, "named argument '%s' is not supported here", a.Name)
+		}
+		kinds[i] = c.kind(a.Value)
+	}
+	return kinds
+}
+
+func (c *checker) kind(expr parser.Expr) string {
+	switch e := expr.(type) {
+	case *parser.Ident:
+		kind, ok := c.vars[e.Name]
+		if !ok {
+			c.errorf(e, "undefined variable '%s'", e.Name)
+			return "any"
+		}
+		return kind
+	case *parser.StringLit:
+		return "string"
+	case *parser.NumberLit:
+		if !strings.ContainsAny(e.Raw, ".eE") && e.Value == math.Trunc(e.Value) {
+			return "integer"
+		}
+		return "number"
+	case *parser.BoolLit:
+		return "boolean"
+	case *parser.NullLit:
+		return "null"
+	case *parser.ArrayLit:
+		for _, el := range e.Elems {
+			c.kind(el)
+		}
+		return "array"
+	case *parser.ObjectLit:
+		for _, f := range e.Fields {
+			c.kind(f.Value)
+		}
+		return "object"
+	case *parser.Dot:
+		return "any"
+	case *parser.MemberExpr:
+		if e.X == nil {
+			return "any"
+		}
+		x := c.kind(e.X)
+		switch {
+		case e.Name == "length" && (x == "string" || x == "array"):
+			return "integer"
+		case known(x) && x != "object":
+			c.errorf(e, "%s has no field '%s'", x, e.Name)
+		}
+		return "any"
+	case *parser.IndexExpr:
+		x := "any"
+		if e.X != nil {
+			x = c.kind(e.X)
+		}
+		c.kind(e.Index)
+		switch x {
+		case "string":
+			return "string"
+		case "integer", "number", "boolean":
+			c.errorf(e, "cannot index %s", x)
+		}
+		return "any"
+	case *parser.CallExpr:
+		return c.call(e)
+	case *parser.PipeExpr:
+		return c.apply(e, e.Filter, []string{c.kind(e.X)}, e.Args)
+	case *parser.UnaryExpr:
+		x := c.kind(e.X)
+		if e.Op == "!" {
+			return "boolean"
+		}
+		if known(x) && !isNumeric(x) {
+			c.errorf(e, "cannot negate %s", x)
+			return "any"
+		}
+		return x
+	case *parser.BinaryExpr:
+		return c.binary(e)
+	}
+	return "any"
+}
+
+func (c *checker) call(e *parser.CallExpr) string {
+	name := stepName(e)
+	if _, isStep := c.registry.Lookup(name); isStep || name == "return" {
+		c.errorf(e, "%s can only be used as a workflow step", name)
+		c.kinds(e.Args)
+		return "any"
+	}
+	switch fun := e.Fun.(type) {
+	case *parser.Ident:
+		return c.apply(e, fun.Name, nil, e.Args)
+	case *parser.MemberExpr:
+		// `http.get(...)` names a step function, not a method of a variable
+		if x, ok := fun.X.(*parser.Ident); ok {
+			if _, isVar := c.vars[x.Name]; !isVar {
+				c.errorf(e, "unknown function '%s.%s'", x.Name, fun.Name)
+				c.kinds(e.Args)
+				return "any"
+			}
+		}
+		recv := "any"
+		if fun.X != nil {
+			recv = c.kind(fun.X)
+		}
+		return c.apply(e, fun.Name, []string{recv}, e.Args)
+	}
+	c.errorf(e, "expression is not callable")
+	return "any"
+}
+
+// apply checks a call of the built-in name on the receiver kinds recv
+// followed by args, and returns the kind of its result
+func (c *checker) apply(e parser.Expr, name string, recv []string, args []*parser.Arg) string {
+	kinds := append(recv, c.kinds(args)...)
+	f, ok := functions[name]
+	if !ok {
+		c.errorf(e, "unknown function '%s'", name)
+		return "any"
+	}
+	return c.checkArgs(e, name, f, kinds)
+}
+
+// checkArgs checks argument kinds against the signature of f and returns
+// the kind of its result
+func (c *checker) checkArgs(e parser.Expr, name string, f function, kinds []string) string {
+	if len(kinds) < f.minArgs || (f.maxArgs >= 0 && len(kinds) > f.maxArgs) {
+		c.errorf(e, "%s expects %s, got %d", name, arity(f), len(kinds))
+		return "any"
+	}
+	for i, kind := range kinds {
+		if want := f.sig.arg(i); !accepts(want, kind) {
+			c.errorf(e, "argument %d of %s must be %s, got %s", i+1, name, strings.ReplaceAll(want, "|", " or "), kind)
+		}
+	}
+	switch f.sig.returns {
+	case sameKind:
+		return kinds[0]
+	case lastKind:
+		return kinds[len(kinds)-1]
+	}
+	return f.sig.returns
+}
+
+func (c *checker) binary(e *parser.BinaryExpr) string {
+	x, y := c.kind(e.X), c.kind(e.Y)
+	switch e.Op {
+	case "&&", "||":
+		if x == y {
+			return x
+		}
+		return "any"
+	case "==", "!=":
+		return "boolean"
+	case "<", "<=", ">", ">=":
+		if known(x) && known(y) && !(isNumeric(x) && isNumeric(y)) && !(x == "string" && y == "string") {
+			c.errorf(e, "cannot compare %s with %s", x, y)
+		}
+		return "boolean"
+	case "+":
+		switch {
+		case x == "string" || y == "string":
+			return "string"
+		case x == "array" && y == "array":
+			return "array"
+		}
+	}
+
+	switch {
+	case known(x) && !isNumeric(x), known(y) && !isNumeric(y):
+		c.errorf(e, "cannot apply '%s' to %s and %s", e.Op, x, y)
+		return "any"
+	case x == "integer" && y == "integer" && e.Op != "/":
+		return "integer"
+	case isNumeric(x) && isNumeric(y):
+		return "number"
+	}
+	return "any"
+}
@@ -0,0 +1,79 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func TestCheckWorkflow(t *testing.T) {
+	intent := workflowIntent("")
+	intent.Outputs = []parser.Output{
+		{Name: "count", Type: "integer"},
+		{Name: "words", Type: "array"},
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string // empty when the workflow is valid
+	}{
+		{"library steps", `→ words = split(text, " ") | filter(. != "")
+→ map(words, .length)
+→ return(count=count(words), words=words, longest=max(result))`, ""},
+		{"transform selectors", `→ transform({ a: text }, { upper: .a | upper, n: limit + 1 })`, ""},
+		{"unknown function", `→ summarize(text)`, "1:12: summarize(text): unknown function 'summarize'"},
+		{"unknown step function", `→ file.read(text)`, "unknown function 'file.read'"},
+		{"http step", `→ http.get("https://example.com/{text}", { accept: "json" })`, ""},
+		{"http arguments", `→ http.post(limit)`, "http.post expects 2 to 3 arguments, got 1"},
+		{"http url kind", `→ http.get(limit)`, "argument 1 of http.get must be string, got integer"},
+		{"nested http", `→ length(http.get(text))`, "http.get can only be used as a workflow step"},
+		{"unknown method", `→ text.shout()`, "unknown function 'shout'"},
+		{"arity", `→ map(split(text, " "))`, "map expects 2 arguments, got 1"},
+		{"argument kind", `→ upper(limit)`, "argument 1 of upper must be string, got integer"},
+		{"result kind", "→ length(text)\n→ result | upper", "argument 1 of upper must be string, got integer"},
+		{"named step kind", "→ n = count(text)\n→ n.trim()", "argument 1 of trim must be string, got integer"},
+		{"output kind", `→ return(count=text)`, "output 'count' is declared integer, got string"},
+		{"operator", `→ text - limit`, "cannot apply '-' to string and integer"},
+		{"undefined variable", `→ upper(txt)`, "undefined variable 'txt'"},
+		{"nested log", `→ upper(log("x"))`, "log can only be used as a workflow step"},
+		{"parallel results", "→ r = parallel:\n  → a = upper(text)\n  → b = length(text)\n→ r.a + a + upper(b)", "argument 1 of upper must be string, got integer"},
+		{"parallel sibling", "→ parallel:\n  → a = upper(text)\n  → b = lower(a)", "undefined variable 'a'"},
+		{"parallel return", "→ parallel:\n  → if true:\n    → return(count=1)", "return cannot be used inside parallel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps, err := parser.ParseWorkflow("workflow", tt.script)
+			if err != nil {
+				t.Fatalf("ParseWorkflow failed: %v", err)
+			}
+			err = CheckWorkflow(intent, steps, Options{})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCheckWorkflow_ReportsAllProblems(t *testing.T) {
+	steps, err := parser.ParseWorkflow("workflow", "→ nosuch(text)\n→ file.read(text)")
+	if err != nil {
+		t.Fatalf("ParseWorkflow failed: %v", err)
+	}
+	err = CheckWorkflow(workflowIntent(""), steps, Options{})
+	var diags parser.Diagnostics
+	if !errors.As(err, &diags) || len(diags) != 2 {
+		t.Fatalf("Expected two diagnostics, got %v", err)
+	}
+	if diags[1].Pos.Line != 2 {
+		t.Errorf("Expected second diagnostic on line 2, got %s", diags[1].Pos)
+	}
+}
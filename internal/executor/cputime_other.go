@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package executor
+
+import "time"
+
+// processCPUTime is not available on this platform, so the CPU time of
+// scripts is not limited; their timeout still applies
+func processCPUTime() time.Duration {
+	return 0
+}
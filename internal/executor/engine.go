@@ -1,43 +1,227 @@
 package executor
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/intentregistry/intent-cli/internal/pack"
 	"github.com/intentregistry/intent-cli/internal/parser"
 )
 
 // ExecuteResult represents the result of an intent execution
 type ExecuteResult map[string]interface{}
 
+// Options configures an execution
+type Options struct {
+	OutputDir string
+	// Network is the outbound policy for http steps, usually read from the
+	// package's itpkg.json. Nil means the intent is not part of a package
+	// and may reach any host, within the default timeout and body size.
+	Network *pack.NetworkPolicy
+	// Capabilities are those the package declares; steps requiring others
+	// fail. Nil grants every capability to workflow steps, for intents
+	// outside a package, but nothing to scripts.
+	Capabilities []string
+	// PackageDir is the directory holding the package's itpkg.json. The
+	// files scripts read and write are confined to it, or to the working
+	// directory when it is empty.
+	PackageDir string
+	// Steps are the workflow steps available; nil uses DefaultRegistry
+	Steps *Registry
+	// Scripts limits javascript:, python: and exec: scripts; nil applies
+	// the defaults
+	Scripts *pack.ScriptPolicy
+	// Mock fills the declared outputs of an intent without a script with
+	// placeholder values instead of failing
+	Mock bool
+	// Observer, if set, receives an event as the execution and each of
+	// its workflow steps starts and ends
+	Observer Observer
+	// Cassette, if set, records the HTTP requests and plugin calls of the
+	// execution, or replays them from a loaded cassette
+	Cassette *Cassette
+	// Cache, if set, keeps the results of intents declaring a cache
+	// duration and reuses them for the same inputs until they expire
+	Cache *Cache
+	// RefreshCache runs the intent even when the cache holds its results,
+	// and stores the new ones
+	RefreshCache bool
+}
+
 // Execute executes an intent with the given parameters
 func Execute(intent *parser.Intent, inputParams map[string]string, outputDir string) (ExecuteResult, error) {
+	return ExecuteWithOptions(intent, inputParams, Options{OutputDir: outputDir})
+}
+
+// ExecuteWithOptions executes an intent with the given parameters and options
+func ExecuteWithOptions(intent *parser.Intent, inputParams map[string]string, opts Options) (ExecuteResult, error) {
+	return ExecuteContext(context.Background(), intent, inputParams, opts)
+}
+
+// Errors returned when the context of an execution ends before it does.
+// The returned error wraps one of them and describes where it stopped.
+var (
+	ErrCanceled = errors.New("execution canceled")
+	ErrTimeout  = errors.New("execution timed out")
+)
+
+// ExecuteContext executes an intent with the given parameters and options.
+// Every step receives ctx; when it is canceled or its deadline passes, the
+// running step is abandoned and the error wraps ErrCanceled or ErrTimeout.
+func ExecuteContext(ctx context.Context, intent *parser.Intent, inputParams map[string]string, opts Options) (ExecuteResult, error) {
+	// Resolve typed parameter values (inputs converted, defaults applied)
+	values, err := intent.ResolveParameters(inputParams)
+	if err != nil {
+		return nil, err
+	}
+	
 	// Prepare execution context
-	context := &ExecutionContext{
-		Intent:     intent,
-		Inputs:     inputParams,
-		OutputDir:  outputDir,
-		Results:    make(ExecuteResult),
+	execCtx := &ExecutionContext{
+		Context:      ctx,
+		Intent:       intent,
+		Inputs:       inputParams,
+		Values:       values,
+		OutputDir:    opts.OutputDir,
+		Network:      opts.Network,
+		Capabilities: opts.Capabilities,
+		PackageDir:   opts.PackageDir,
+		Steps:        opts.Steps,
+		Scripts:      opts.Scripts,
+		Mock:         opts.Mock,
+		Observer:     opts.Observer,
+		Cassette:     opts.Cassette,
+		Cache:        opts.Cache,
+		RefreshCache: opts.RefreshCache,
+		Results:      make(ExecuteResult),
+		span:         executionSpan,
+	}
+	if execCtx.Steps == nil {
+		execCtx.Steps = DefaultRegistry
+	}
+	if execCtx.Scripts == nil {
+		execCtx.Scripts = pack.DefaultScriptPolicy()
+	}
+	execCtx.secrets = newSecretValues(intent, values, nil)
+	opts.Cassette.hide(execCtx.secrets)
+	
+	results, err := run(execCtx, 0)
+	return execCtx.secrets.redactResults(results), execCtx.secrets.redactError(err)
+}
+
+// run takes a prepared execution's results from the cache, or executes it
+// and caches them, reporting its start and end as events under the parent
+// span (0 for none). Secrets are masked in the events and the cache but
+// not in the returned results, which a calling workflow may still use.
+func run(ctx *ExecutionContext, parent int64) (ExecuteResult, error) {
+	cached, cacheKey, err := cachedExecution(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	ctx.observe(Event{Type: EventExecutionStart, Span: ctx.span, Parent: parent, Inputs: ctx.Values})
+	start := time.Now()
+	results := cached
+	if results == nil {
+		results, err = execute(ctx)
+	}
+	end := Event{Type: EventExecutionEnd, Span: ctx.span, Parent: parent, Duration: time.Since(start), Cached: cached != nil}
+	if err != nil {
+		end.Error = err.Error()
+	} else {
+		end.Output = results
+	}
+	ctx.observe(end)
+	
+	// Failed executions are not cached, so that they are retried
+	if err == nil && cached == nil && cacheKey != "" {
+		ttl, _ := ctx.Intent.CacheTTL()
+		if err := ctx.Cache.Put(cacheKey, ctx.Intent, ctx.secrets.redactResults(results), ttl); err != nil {
+			fmt.Printf("Warning: failed to cache results: %v\n", err)
+		}
+	}
+	return results, err
+}
+
+// execute runs a prepared execution and checks its result
+func execute(execCtx *ExecutionContext) (ExecuteResult, error) {
+	ctx := execCtx.Context
+	if err := contextError(ctx, nil); err != nil {
+		return nil, err
 	}
 	
 	// Execute based on intent type
-	if intent.Script != "" {
-		return executeScriptIntent(context)
+	var results ExecuteResult
+	var err error
+	if execCtx.Intent.Script != "" {
+		results, err = executeScriptIntent(execCtx)
+	} else {
+		// Default execution for intents without scripts
+		results, err = executeDefaultIntent(execCtx)
+	}
+	if err != nil {
+		if ctxErr := contextError(ctx, err); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
 	}
 	
-	// Default execution for intents without scripts
-	return executeDefaultIntent(context)
+	// The result must provide every declared output
+	if err := ValidateOutputs(execCtx.Intent, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// contextError returns the error reporting that ctx has ended, wrapping
+// ErrCanceled or ErrTimeout and the error cause of the step it stopped,
+// or nil while ctx is still active
+func contextError(ctx context.Context, cause error) error {
+	var sentinel error
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		sentinel = ErrTimeout
+	default:
+		sentinel = ErrCanceled
+	}
+	if cause == nil {
+		return sentinel
+	}
+	return fmt.Errorf("%w: %v", sentinel, cause)
 }
 
 // ExecutionContext holds the execution state
 type ExecutionContext struct {
-	Intent    *parser.Intent
-	Inputs    map[string]string
-	OutputDir string
-	Results   ExecuteResult
+	Context      context.Context // ends when the execution must stop
+	Intent       *parser.Intent
+	Inputs       map[string]string
+	Values       map[string]interface{} // typed parameter values, defaults included
+	OutputDir    string
+	Network      *pack.NetworkPolicy // nil when no package policy applies
+	Capabilities []string            // nil grants every capability to steps, none to scripts
+	PackageDir   string              // confines the files scripts use; empty means the working directory
+	Steps        *Registry
+	Scripts      *pack.ScriptPolicy
+	Mock         bool // generate placeholder outputs for intents without a script
+	Observer     Observer
+	Cassette     *Cassette // nil makes external calls for real
+	Cache        *Cache    // nil disables caching
+	RefreshCache bool
+	Results      ExecuteResult
+
+	span    int64             // span of the execution itself in events
+	spans   atomic.Int64      // step spans started so far
+	caller  *ExecutionContext // the execution whose call step runs this one, which numbers its spans
+	callers []string          // absolute paths of the intents that called this one, outermost first
+	secrets secretValues      // masked in events, logs, results and errors
 }
 
 // executeScriptIntent executes an intent with a custom script
@@ -47,113 +231,49 @@ func executeScriptIntent(ctx *ExecutionContext) (ExecuteResult, error) {
 		return executeWorkflowScript(ctx)
 	}
 	
-	// For now, we'll implement a simple script execution
-	// In a real implementation, you might support JavaScript, Python, or other languages
-	
+	// JavaScript runs embedded; other languages run as subprocesses
 	script := ctx.Intent.Script
 	if strings.HasPrefix(script, "javascript:") {
 		return executeJavaScript(script[11:], ctx)
 	}
 	
 	if strings.HasPrefix(script, "python:") {
-		return executePython(script[7:], ctx)
+		return executeSubprocess(pythonInterpreter(), script[7:], ctx)
+	}
+	
+	if strings.HasPrefix(script, "exec:") {
+		interpreter, code := splitInterpreter(script[5:])
+		return executeSubprocess(interpreter, code, ctx)
 	}
 	
 	// Default to simple template execution
 	return executeTemplate(script, ctx)
 }
 
-// executeWorkflowScript executes a workflow script with → commands
-func executeWorkflowScript(ctx *ExecutionContext) (ExecuteResult, error) {
-	lines := strings.Split(ctx.Intent.Script, "\n")
-	results := make(ExecuteResult)
-	
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		
-		// Remove → prefix if present (handle different arrow characters)
-		if strings.HasPrefix(line, "→ ") {
-			line = line[2:]
-		} else if strings.HasPrefix(line, "→") {
-			line = line[1:]
-		}
-		
-		// Parse workflow commands
-		if strings.HasPrefix(line, "log(") && strings.HasSuffix(line, ")") {
-			// Extract message from log("message")
-			message := line[4:len(line)-1] // Remove "log(" and ")"
-			message = strings.Trim(message, `"`) // Remove quotes
-			// Process template in message
-			processedMessage := processTemplate(message, ctx)
-			results["result"] = processedMessage
-		} else if strings.HasPrefix(line, "return(") && strings.HasSuffix(line, ")") {
-			// Extract return values from return(key="value")
-			returnPart := line[7:len(line)-1] // Remove "return(" and ")"
-			// Simple parsing for now - just set status
-			if strings.Contains(returnPart, "status=") {
-				statusStart := strings.Index(returnPart, "status=")
-				statusValue := returnPart[statusStart+7:]
-				statusValue = strings.Trim(statusValue, `"`)
-				results["status"] = statusValue
-			}
-		}
+// templateScope returns the variables visible to workflow expressions and
+// templates: every parameter by name, plus `input` holding all of them
+func templateScope(ctx *ExecutionContext) map[string]interface{} {
+	scope := make(map[string]interface{}, len(ctx.Values)+1)
+	input := make(map[string]interface{}, len(ctx.Values))
+	for key, value := range ctx.Values {
+		scope[key] = value
+		input[key] = value
 	}
-	
-	// Ensure we have at least a status
-	if results["status"] == nil {
-		results["status"] = "success"
-	}
-	
-	return results, nil
+	scope["input"] = input
+	return scope
 }
 
-// processTemplate processes template variables in a string
-func processTemplate(template string, ctx *ExecutionContext) string {
-	result := template
-	
-	// Create a map of all parameter values (inputs + defaults)
-	paramValues := make(map[string]string)
-	
-	// First, add default values from intent definition
-	for _, param := range ctx.Intent.Parameters {
-		if param.Default != nil {
-			// Convert default value to string
-			if defaultStr, ok := param.Default.(string); ok {
-				paramValues[param.Name] = defaultStr
-			} else {
-				// Convert other types to string
-				paramValues[param.Name] = fmt.Sprintf("%v", param.Default)
-			}
-		}
-	}
-	
-	// Then, override with provided input values
-	for key, value := range ctx.Inputs {
-		paramValues[key] = value
-	}
-	
-	// Replace parameter placeholders (support both {{name}} and {name} syntax)
-	for key, value := range paramValues {
-		// Support {{name}} syntax
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-		
-		// Support {name} syntax
-		placeholder = fmt.Sprintf("{%s}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-	}
-	
-	return result
-}
-
-// executeDefaultIntent executes an intent without a custom script
+// executeDefaultIntent executes an intent without a custom script. Only
+// the result, status and message outputs can be produced; other outputs
+// get placeholder values in mock mode and are an error otherwise.
 func executeDefaultIntent(ctx *ExecutionContext) (ExecuteResult, error) {
 	// Simple execution that processes inputs and generates outputs
 	results := make(ExecuteResult)
 	
+	if err := checkUnscripted(ctx.Intent, ctx.Mock); err != nil {
+		return nil, err
+	}
+	
 	// Process each output
 	for _, output := range ctx.Intent.Outputs {
 		switch output.Name {
@@ -171,7 +291,7 @@ func executeDefaultIntent(ctx *ExecutionContext) (ExecuteResult, error) {
 	
 	// Save results to output directory if specified
 	if ctx.OutputDir != "" {
-		if err := saveResults(results, ctx.OutputDir); err != nil {
+		if err := saveResults(ctx.secrets.redactResults(results), ctx.OutputDir); err != nil {
 			return nil, fmt.Errorf("failed to save results: %w", err)
 		}
 	}
@@ -179,99 +299,112 @@ func executeDefaultIntent(ctx *ExecutionContext) (ExecuteResult, error) {
 	return results, nil
 }
 
-// executeJavaScript executes JavaScript code (placeholder)
-func executeJavaScript(code string, ctx *ExecutionContext) (ExecuteResult, error) {
-	// In a real implementation, you'd use a JavaScript engine like goja
-	// For now, return a placeholder result
-	return ExecuteResult{
-		"result": "JavaScript execution not yet implemented",
-		"status": "error",
-	}, fmt.Errorf("JavaScript execution not yet implemented")
-}
-
-// executePython executes Python code (placeholder)
-func executePython(code string, ctx *ExecutionContext) (ExecuteResult, error) {
-	// In a real implementation, you'd use a Python interpreter
-	// For now, return a placeholder result
-	return ExecuteResult{
-		"result": "Python execution not yet implemented",
-		"status": "error",
-	}, fmt.Errorf("Python execution not yet implemented")
+// checkUnscripted reports the outputs an intent without a script cannot
+// produce, unless mock provides placeholder values for them
+func checkUnscripted(intent *parser.Intent, mock bool) error {
+	var missing []string
+	for _, output := range intent.Outputs {
+		switch output.Name {
+		case "result", "status", "message":
+		default:
+			missing = append(missing, output.Name)
+		}
+	}
+	if len(missing) > 0 && !mock {
+		return fmt.Errorf("intent '%s' has no script to produce %s (use --mock for placeholder values)", intent.Name, strings.Join(missing, ", "))
+	}
+	return nil
 }
 
 // executeTemplate executes a simple template
 func executeTemplate(template string, ctx *ExecutionContext) (ExecuteResult, error) {
-	// Simple template replacement
-	result := template
+	scope := templateScope(ctx)
 	
-	// Create a map of all parameter values (inputs + defaults)
-	paramValues := make(map[string]string)
-	
-	// First, add default values from intent definition
-	for _, param := range ctx.Intent.Parameters {
-		if param.Default != nil {
-			// Convert default value to string
-			if defaultStr, ok := param.Default.(string); ok {
-				paramValues[param.Name] = defaultStr
-			} else {
-				// Convert other types to string
-				paramValues[param.Name] = fmt.Sprintf("%v", param.Default)
-			}
-		}
+	// Intent metadata is available unless a parameter shadows it
+	metadata := map[string]interface{}{
+		"name":        ctx.Intent.Name,
+		"description": ctx.Intent.Description,
+		"version":     ctx.Intent.Version,
 	}
-	
-	// Then, override with provided input values
-	for key, value := range ctx.Inputs {
-		paramValues[key] = value
+	for key, value := range metadata {
+		if _, ok := scope[key]; !ok {
+			scope[key] = value
+		}
 	}
 	
-	// Replace parameter placeholders (support both {{name}} and {name} syntax)
-	for key, value := range paramValues {
-		// Support {{name}} syntax
-		placeholder := fmt.Sprintf("{{%s}}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
-		
-		// Support {name} syntax
-		placeholder = fmt.Sprintf("{%s}", key)
-		result = strings.ReplaceAll(result, placeholder, value)
+	result, err := RenderTemplate(template, scope)
+	if err != nil {
+		return nil, fmt.Errorf("template error: %w", err)
 	}
 	
-	// Replace intent metadata
-	result = strings.ReplaceAll(result, "{{name}}", ctx.Intent.Name)
-	result = strings.ReplaceAll(result, "{{description}}", ctx.Intent.Description)
-	result = strings.ReplaceAll(result, "{{version}}", ctx.Intent.Version)
-	
 	return ExecuteResult{
-		"result": result,
-		"status": "success",
+		resultName(ctx.Intent): result,
+		"status":               "success",
 	}, nil
 }
 
+// resultName returns the key a script's single value is stored under: the
+// intent's only declared output besides status, or `result`
+func resultName(intent *parser.Intent) string {
+	name := "result"
+	count := 0
+	for _, output := range intent.Outputs {
+		if output.Name != "status" {
+			name = output.Name
+			count++
+		}
+	}
+	if count != 1 {
+		return "result"
+	}
+	return name
+}
+
 // processDefaultResult processes inputs to generate a default result
 func processDefaultResult(ctx *ExecutionContext) string {
 	if len(ctx.Inputs) == 0 {
 		return fmt.Sprintf("Intent '%s' executed with no inputs", ctx.Intent.Name)
 	}
 	
+	// List inputs in declaration order so the result is deterministic
 	var parts []string
-	for key, value := range ctx.Inputs {
-		parts = append(parts, fmt.Sprintf("%s=%s", key, value))
+	for _, param := range ctx.Intent.Parameters {
+		if value, ok := ctx.Inputs[param.Name]; ok {
+			if param.Type == "secret" {
+				value = secretMask
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s", param.Name, value))
+		}
 	}
 	
 	return fmt.Sprintf("Intent '%s' processed inputs: %s", ctx.Intent.Name, strings.Join(parts, ", "))
 }
 
-// generateOutput generates output based on the output definition
+// generateOutput generates a placeholder value for the output definition
+// in mock mode. Values have the declared type and format.
 func generateOutput(output parser.Output, ctx *ExecutionContext) interface{} {
+	switch strings.ToLower(output.Format) {
+	case "json":
+		if typeKind(output.Type) == "string" {
+			return fmt.Sprintf(`{"output": %q}`, output.Name)
+		}
+	case "uri":
+		return "https://example.com/" + output.Name
+	}
+
 	switch output.Type {
-	case "string", "text":
+	case "string", "text", "file":
 		return fmt.Sprintf("Generated %s output", output.Name)
-	case "number", "integer", "float":
-		return 42
+	case "url":
+		return "https://example.com/" + output.Name
+	case "integer":
+		return int64(42)
+	case "number", "float":
+		return 42.0
 	case "boolean":
 		return true
 	case "array":
-		return []string{"item1", "item2", "item3"}
+		return []interface{}{"item1", "item2", "item3"}
 	case "object", "json":
 		return map[string]interface{}{
 			"output": output.Name,
@@ -314,97 +447,3 @@ func saveResults(results ExecuteResult, outputDir string) error {
 	
 	return nil
 }
-
-// ValidateInputs validates input parameters against intent definition
-func ValidateInputs(intent *parser.Intent, inputParams map[string]string) error {
-	// Check required parameters
-	for _, param := range intent.Parameters {
-		if param.Required {
-			if _, exists := inputParams[param.Name]; !exists {
-				return fmt.Errorf("required parameter '%s' not provided", param.Name)
-			}
-		}
-	}
-	
-	// Validate parameter values
-	for name, value := range inputParams {
-		// Find parameter definition
-		var param *parser.Parameter
-		for _, p := range intent.Parameters {
-			if p.Name == name {
-				param = &p
-				break
-			}
-		}
-		
-		if param == nil {
-			return fmt.Errorf("unknown parameter '%s'", name)
-		}
-		
-		// Validate value
-		if err := validateParameterValue(value, param); err != nil {
-			return fmt.Errorf("invalid value for parameter '%s': %w", name, err)
-		}
-	}
-	
-	return nil
-}
-
-// validateParameterValue validates a parameter value against its definition
-func validateParameterValue(value string, param *parser.Parameter) error {
-	// Basic validation based on type
-	switch param.Type {
-	case "string", "text":
-		// String validation
-		if param.Validation.Pattern != "" {
-			// In a real implementation, you'd use regex
-			// For now, just check if it's not empty
-			if value == "" {
-				return fmt.Errorf("value cannot be empty")
-			}
-		}
-	case "number", "integer", "float":
-		// Number validation
-		if param.Validation.Min != nil || param.Validation.Max != nil {
-			// In a real implementation, you'd parse the number and check bounds
-			// For now, just check if it's not empty
-			if value == "" {
-				return fmt.Errorf("value cannot be empty")
-			}
-		}
-	case "boolean":
-		// Boolean validation
-		lowerValue := strings.ToLower(value)
-		if lowerValue != "true" && lowerValue != "false" {
-			return fmt.Errorf("boolean value must be 'true' or 'false'")
-		}
-	case "array":
-		// Array validation - check if it's valid JSON array
-		var arr []interface{}
-		if err := json.Unmarshal([]byte(value), &arr); err != nil {
-			return fmt.Errorf("invalid array format")
-		}
-	case "object", "json":
-		// Object validation - check if it's valid JSON object
-		var obj map[string]interface{}
-		if err := json.Unmarshal([]byte(value), &obj); err != nil {
-			return fmt.Errorf("invalid object format")
-		}
-	}
-	
-	// Check options if specified
-	if len(param.Validation.Options) > 0 {
-		found := false
-		for _, option := range param.Validation.Options {
-			if value == option {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return fmt.Errorf("value must be one of: %s", strings.Join(param.Validation.Options, ", "))
-		}
-	}
-	
-	return nil
-}
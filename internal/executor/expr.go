@@ -0,0 +1,575 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// EvalError reports an expression that could not be evaluated
+type EvalError struct {
+	Expr    string
+	Message string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Expr, e.Message)
+}
+
+func evalErrorf(node parser.Expr, format string, args ...interface{}) error {
+	return &EvalError{Expr: node.String(), Message: fmt.Sprintf(format, args...)}
+}
+
+// EvalExpr evaluates an expression against the variables in scope.
+// Referencing a name that is not in scope is an error.
+func EvalExpr(expr parser.Expr, scope map[string]interface{}) (interface{}, error) {
+	ev := &evaluator{scope: scope}
+	return ev.eval(expr)
+}
+
+// RenderTemplate replaces every `{expr}` or `{{expr}}` in tmpl with the
+// value of expr. Braces whose contents do not parse as an expression, such
+// as JSON text, are copied unchanged; a literal brace can always be
+// written as `{"{"}`.
+func RenderTemplate(tmpl string, scope map[string]interface{}) (string, error) {
+	ev := &evaluator{scope: scope}
+	return ev.render(tmpl)
+}
+
+// evaluator walks expression trees. dot is the current value that
+// jq-style selectors such as `.main.temp` are resolved against.
+type evaluator struct {
+	scope  map[string]interface{}
+	dot    interface{}
+	hasDot bool
+}
+
+func (ev *evaluator) eval(expr parser.Expr) (interface{}, error) {
+	switch e := expr.(type) {
+	case *parser.Ident:
+		v, ok := ev.scope[e.Name]
+		if !ok {
+			return nil, evalErrorf(e, "undefined variable '%s'", e.Name)
+		}
+		return v, nil
+	case *parser.StringLit:
+		return ev.render(e.Value)
+	case *parser.NumberLit:
+		if !strings.ContainsAny(e.Raw, ".eE") && e.Value == math.Trunc(e.Value) {
+			return int64(e.Value), nil
+		}
+		return e.Value, nil
+	case *parser.BoolLit:
+		return e.Value, nil
+	case *parser.NullLit:
+		return nil, nil
+	case *parser.ArrayLit:
+		arr := make([]interface{}, len(e.Elems))
+		for i, el := range e.Elems {
+			v, err := ev.eval(el)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = v
+		}
+		return arr, nil
+	case *parser.ObjectLit:
+		obj := make(map[string]interface{}, len(e.Fields))
+		for _, f := range e.Fields {
+			v, err := ev.eval(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.Key] = v
+		}
+		return obj, nil
+	case *parser.Dot:
+		return ev.receiver(e, nil)
+	case *parser.MemberExpr:
+		x, err := ev.receiver(e, e.X)
+		if err != nil {
+			return nil, err
+		}
+		return member(e, x, e.Name)
+	case *parser.IndexExpr:
+		x, err := ev.receiver(e, e.X)
+		if err != nil {
+			return nil, err
+		}
+		index, err := ev.eval(e.Index)
+		if err != nil {
+			return nil, err
+		}
+		return indexValue(e, x, index)
+	case *parser.CallExpr:
+		return ev.call(e)
+	case *parser.PipeExpr:
+		x, err := ev.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		return ev.invoke(e, e.Filter, []interface{}{x}, e.Args)
+	case *parser.UnaryExpr:
+		x, err := ev.eval(e.X)
+		if err != nil {
+			return nil, err
+		}
+		if e.Op == "!" {
+			return !truthy(x), nil
+		}
+		switch n := x.(type) {
+		case int64:
+			return -n, nil
+		case float64:
+			return -n, nil
+		}
+		return nil, evalErrorf(e, "cannot negate %s", typeName(x))
+	case *parser.BinaryExpr:
+		return ev.binary(e)
+	}
+	return nil, evalErrorf(expr, "unsupported expression")
+}
+
+// receiver evaluates the left side of a member or index expression; a nil
+// x refers to the current value of a selector.
+func (ev *evaluator) receiver(node, x parser.Expr) (interface{}, error) {
+	if x != nil {
+		return ev.eval(x)
+	}
+	if !ev.hasDot {
+		return nil, evalErrorf(node, "selector used without a current value")
+	}
+	return ev.dot, nil
+}
+
+func (ev *evaluator) args(args []*parser.Arg) ([]interface{}, error) {
+	values := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, evalErrorf(a.Value, "named argument '%s' is not supported here", a.Name)
+		}
+		v, err := ev.eval(a.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+// call evaluates `fn(args)` and method calls `x.fn(args)`, which pass x
+// as the first argument.
+func (ev *evaluator) call(e *parser.CallExpr) (interface{}, error) {
+	switch fun := e.Fun.(type) {
+	case *parser.Ident:
+		return ev.invoke(e, fun.Name, nil, e.Args)
+	case *parser.MemberExpr:
+		x, err := ev.receiver(fun, fun.X)
+		if err != nil {
+			return nil, err
+		}
+		return ev.invoke(e, fun.Name, []interface{}{x}, e.Args)
+	}
+	return nil, evalErrorf(e, "expression is not callable")
+}
+
+// invoke calls the built-in name with recv, the receiver of a method or
+// the input of a filter, followed by args. Forms get their trailing
+// arguments unevaluated.
+func (ev *evaluator) invoke(e parser.Expr, name string, recv []interface{}, args []*parser.Arg) (interface{}, error) {
+	form, ok := forms[name]
+	if !ok {
+		values, err := ev.args(args)
+		if err != nil {
+			return nil, err
+		}
+		return callFunction(e, name, append(recv, values...))
+	}
+
+	exprs := make([]parser.Expr, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			return nil, evalErrorf(a.Value, "named argument '%s' is not supported here", a.Name)
+		}
+		exprs[i] = a.Value
+	}
+	if _, err := lookupFunction(e, name, len(recv)+len(exprs)); err != nil {
+		return nil, err
+	}
+	if len(recv) == 0 {
+		x, err := ev.eval(exprs[0])
+		if err != nil {
+			return nil, err
+		}
+		recv, exprs = []interface{}{x}, exprs[1:]
+	}
+	result, err := form(ev, recv[0], exprs)
+	return result, functionError(e, name, err)
+}
+
+// with returns an evaluator for the same scope whose current value is dot
+func (ev *evaluator) with(dot interface{}) *evaluator {
+	return &evaluator{scope: ev.scope, dot: dot, hasDot: true}
+}
+
+func (ev *evaluator) binary(e *parser.BinaryExpr) (interface{}, error) {
+	x, err := ev.eval(e.X)
+	if err != nil {
+		return nil, err
+	}
+
+	// Logical operators short-circuit and yield the deciding operand,
+	// so `name || "anonymous"` works as a fallback.
+	switch e.Op {
+	case "&&":
+		if !truthy(x) {
+			return x, nil
+		}
+		return ev.eval(e.Y)
+	case "||":
+		if truthy(x) {
+			return x, nil
+		}
+		return ev.eval(e.Y)
+	}
+
+	y, err := ev.eval(e.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Op {
+	case "==":
+		return equalValues(x, y), nil
+	case "!=":
+		return !equalValues(x, y), nil
+	case "<", "<=", ">", ">=":
+		c, err := compareValues(e, x, y)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case "+":
+		if xs, ok := x.(string); ok {
+			return xs + formatValue(y), nil
+		}
+		if ys, ok := y.(string); ok {
+			return formatValue(x) + ys, nil
+		}
+		if xa, ok := x.([]interface{}); ok {
+			if ya, ok := y.([]interface{}); ok {
+				return append(append([]interface{}{}, xa...), ya...), nil
+			}
+		}
+	}
+	return arithmetic(e, x, y)
+}
+
+// arithmetic applies + - * / % to numbers. Integer operands stay integers
+// except for division with a remainder.
+func arithmetic(e *parser.BinaryExpr, x, y interface{}) (interface{}, error) {
+	xi, xInt := x.(int64)
+	yi, yInt := y.(int64)
+	if xInt && yInt {
+		switch e.Op {
+		case "+":
+			return xi + yi, nil
+		case "-":
+			return xi - yi, nil
+		case "*":
+			return xi * yi, nil
+		case "/", "%":
+			if yi == 0 {
+				return nil, evalErrorf(e, "division by zero")
+			}
+			if e.Op == "%" {
+				return xi % yi, nil
+			}
+			if xi%yi == 0 {
+				return xi / yi, nil
+			}
+		}
+	}
+
+	xf, xok := toNumber(x)
+	yf, yok := toNumber(y)
+	if !xok || !yok {
+		return nil, evalErrorf(e, "cannot apply '%s' to %s and %s", e.Op, typeName(x), typeName(y))
+	}
+	switch e.Op {
+	case "+":
+		return xf + yf, nil
+	case "-":
+		return xf - yf, nil
+	case "*":
+		return xf * yf, nil
+	case "/":
+		if yf == 0 {
+			return nil, evalErrorf(e, "division by zero")
+		}
+		return xf / yf, nil
+	default:
+		if yf == 0 {
+			return nil, evalErrorf(e, "division by zero")
+		}
+		return math.Mod(xf, yf), nil
+	}
+}
+
+func compareValues(e parser.Expr, x, y interface{}) (int, error) {
+	if xf, ok := toNumber(x); ok {
+		if yf, ok := toNumber(y); ok {
+			switch {
+			case xf < yf:
+				return -1, nil
+			case xf > yf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	if xs, ok := x.(string); ok {
+		if ys, ok := y.(string); ok {
+			return strings.Compare(xs, ys), nil
+		}
+	}
+	return 0, evalErrorf(e, "cannot compare %s with %s", typeName(x), typeName(y))
+}
+
+func equalValues(x, y interface{}) bool {
+	if xf, ok := toNumber(x); ok {
+		if yf, ok := toNumber(y); ok {
+			return xf == yf
+		}
+	}
+	return reflect.DeepEqual(x, y)
+}
+
+// member resolves `x.name`. Objects return nil for missing keys, like jq;
+// strings and arrays expose `length`.
+func member(e parser.Expr, x interface{}, name string) (interface{}, error) {
+	switch v := x.(type) {
+	case map[string]interface{}:
+		if value, ok := v[name]; ok || name != "length" {
+			return value, nil
+		}
+		return int64(len(v)), nil
+	case string:
+		if name == "length" {
+			return int64(utf8.RuneCountInString(v)), nil
+		}
+	case []interface{}:
+		if name == "length" {
+			return int64(len(v)), nil
+		}
+	case nil:
+		return nil, nil
+	}
+	return nil, evalErrorf(e, "%s has no field '%s'", typeName(x), name)
+}
+
+// indexValue resolves `x[index]`. Negative indexes count from the end and
+// out-of-range indexes yield null.
+func indexValue(e parser.Expr, x, index interface{}) (interface{}, error) {
+	switch v := x.(type) {
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, evalErrorf(e, "object index must be a string, got %s", typeName(index))
+		}
+		return v[key], nil
+	case []interface{}:
+		i, err := intIndex(e, index, len(v))
+		if err != nil || i < 0 {
+			return nil, err
+		}
+		return v[i], nil
+	case string:
+		runes := []rune(v)
+		i, err := intIndex(e, index, len(runes))
+		if err != nil || i < 0 {
+			return nil, err
+		}
+		return string(runes[i]), nil
+	case nil:
+		return nil, nil
+	}
+	return nil, evalErrorf(e, "cannot index %s", typeName(x))
+}
+
+// intIndex converts index to a position in a sequence of length n,
+// returning -1 when it is out of range
+func intIndex(e parser.Expr, index interface{}, n int) (int, error) {
+	f, ok := toNumber(index)
+	if !ok || f != math.Trunc(f) {
+		return 0, evalErrorf(e, "index must be an integer, got %s", typeName(index))
+	}
+	i := int(f)
+	if i < 0 {
+		i += n
+	}
+	if i < 0 || i >= n {
+		return -1, nil
+	}
+	return i, nil
+}
+
+func (ev *evaluator) render(tmpl string) (string, error) {
+	if !strings.Contains(tmpl, "{") {
+		return tmpl, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(tmpl); {
+		if tmpl[i] != '{' {
+			sb.WriteByte(tmpl[i])
+			i++
+			continue
+		}
+
+		// Prefer {{expr}}; fall back to {expr}
+		src, width := "", 0
+		if strings.HasPrefix(tmpl[i:], "{{") {
+			if end := closingBrace(tmpl, i+2); end >= 0 && strings.HasPrefix(tmpl[end:], "}}") {
+				src, width = tmpl[i+2:end], end+2-i
+			}
+		}
+		if width == 0 {
+			if end := closingBrace(tmpl, i+1); end >= 0 {
+				src, width = tmpl[i+1:end], end+1-i
+			}
+		}
+
+		var expr parser.Expr
+		if strings.TrimSpace(src) != "" {
+			expr, _ = parser.ParseExpr("", src)
+		}
+		if expr == nil {
+			sb.WriteByte('{')
+			i++
+			continue
+		}
+
+		value, err := ev.eval(expr)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(formatValue(value))
+		i += width
+	}
+	return sb.String(), nil
+}
+
+// closingBrace returns the offset of the '}' that closes a brace opened
+// just before start, skipping nested braces and quoted strings, or -1
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			for i++; i < len(s) && s[i] != c; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// formatValue renders a value for interpolation into text. Arrays and
+// objects are written as JSON; null is the empty string.
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case bool:
+		return strconv.FormatBool(val)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(val)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func truthy(v interface{}) bool {
+	switch val := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case []interface{}:
+		return len(val) > 0
+	case map[string]interface{}:
+		return len(val) > 0
+	}
+	if f, ok := toNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// typeName describes a value using ITML type names
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, int:
+		return "integer"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
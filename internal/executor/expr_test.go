@@ -0,0 +1,147 @@
+package executor
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func exprScope() map[string]interface{} {
+	return map[string]interface{}{
+		"text":  "Hello brave world",
+		"count": int64(3),
+		"ratio": 0.5,
+		"tags":  []interface{}{"a", "b", "c"},
+		"user":  map[string]interface{}{"name": "Ann", "age": int64(41)},
+		"empty": "",
+	}
+}
+
+func TestEvalExpr(t *testing.T) {
+	tests := []struct {
+		src  string
+		want interface{}
+	}{
+		{"text.length", int64(17)},
+		{"tags[0]", "a"},
+		{"tags[-1]", "c"},
+		{"tags[5]", nil},
+		{"user.name", "Ann"},
+		{`user["age"] + 1`, int64(42)},
+		{"user.missing", nil},
+		{"count * 2 + 1", int64(7)},
+		{"count / 2", 1.5},
+		{"count % 2", int64(1)},
+		{"ratio * count", 1.5},
+		{"-count", int64(-3)},
+		{`"n=" + count`, "n=3"},
+		{"count > 2 && user.age <= 41", true},
+		{"count == 3.0", true},
+		{`text != "x"`, true},
+		{"!empty", true},
+		{`empty || "fallback"`, "fallback"},
+		{"text | upper", "HELLO BRAVE WORLD"},
+		{"text.substring(0, 5)", "Hello"},
+		{"text.toLowerCase()", "hello brave world"},
+		{`split(text, " ") | length`, int64(3)},
+		{`tags | join("-")`, "a-b-c"},
+		{"tags | reverse | first", "c"},
+		{"text | truncate(8)", "Hello..."},
+		{`tags + ["d"]`, []interface{}{"a", "b", "c", "d"}},
+		{"max(1, count, 2)", int64(3)},
+		{"round(2.567, 2)", 2.57},
+		{`contains(tags, "b")`, true},
+		{`empty | default("none")`, "none"},
+		{`"{user.name} is {user.age}"`, "Ann is 41"},
+		{"map(tags, . | upper)", []interface{}{"A", "B", "C"}},
+		{`tags | filter(. != "b")`, []interface{}{"a", "c"}},
+		{`tags.map("{.}!") | join("")`, "a!b!c!"},
+		{`count(tags, . > "a")`, int64(2)},
+		{"count(user)", int64(2)},
+		{"transform(user, { who: .name, next: .age + count })", map[string]interface{}{"who": "Ann", "next": int64(44)}},
+	}
+	for _, tt := range tests {
+		expr, err := parser.ParseExpr("", tt.src)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.src, err)
+			continue
+		}
+		got, err := EvalExpr(expr, exprScope())
+		if err != nil {
+			t.Errorf("EvalExpr(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EvalExpr(%q) = %#v, want %#v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestEvalExpr_Errors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"missing + 1", "undefined variable 'missing'"},
+		{"count / 0", "division by zero"},
+		{"text - 1", "cannot apply '-' to string and integer"},
+		{"nosuch(text)", "unknown function 'nosuch'"},
+		{"upper(count)", "expected string, got integer"},
+		{"substring(text)", "substring expects 2 to 3 arguments, got 1"},
+		{"count.name", "integer has no field 'name'"},
+		{"tags < 1", "cannot compare array with integer"},
+		{"map(count, .)", "map: expected array, got integer"},
+		{"map(tags)", "map expects 2 arguments, got 1"},
+		{". + 1", "selector used without a current value"},
+	}
+	for _, tt := range tests {
+		expr, err := parser.ParseExpr("", tt.src)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.src, err)
+			continue
+		}
+		_, err = EvalExpr(expr, exprScope())
+		var evalErr *EvalError
+		if !errors.As(err, &evalErr) {
+			t.Errorf("EvalExpr(%q): expected *EvalError, got %v", tt.src, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("EvalExpr(%q) error = %q, want it to contain %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"Hello {{user.name}}!", "Hello Ann!"},
+		{"Length: {text.length}", "Length: 17"},
+		{"{ count * 2 } items", "6 items"},
+		{"Tags: {tags}", `Tags: ["a","b","c"]`},
+		{`{"a": 1, "b": [1, 2]}`, `{"a": 1, "b": [1, 2]}`},
+		{"function() { return; }", "function() { return; }"},
+		{`Literal {"{"}braces{"}"}`, "Literal {braces}"},
+		{"unclosed {count", "unclosed {count"},
+		{"{}", "{}"},
+	}
+	for _, tt := range tests {
+		got, err := RenderTemplate(tt.tmpl, exprScope())
+		if err != nil {
+			t.Errorf("RenderTemplate(%q) failed: %v", tt.tmpl, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if _, err := RenderTemplate("Hi {nmae}", exprScope()); err == nil || !strings.Contains(err.Error(), "undefined variable 'nmae'") {
+		t.Errorf("Expected undefined variable error, got %v", err)
+	}
+}
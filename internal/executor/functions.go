@@ -0,0 +1,582 @@
+package executor
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// function is a built-in usable as a call `f(x, ...)`, a method
+// `x.f(...)` or a filter `x | f(...)`
+type function struct {
+	minArgs int
+	maxArgs int // -1 for variadic
+	sig     signature
+	fn      func(args []interface{}) (interface{}, error)
+}
+
+// form implements a built-in that receives its arguments after the first
+// unevaluated, so it can evaluate them once per element with `.` bound
+// to that element
+type form func(ev *evaluator, x interface{}, args []parser.Expr) (interface{}, error)
+
+// Kind markers for built-ins whose result has the kind of their first
+// or last argument
+const (
+	sameKind = "same"
+	lastKind = "last"
+)
+
+// signature describes the kinds a built-in accepts and returns, for
+// checking workflows before they run. Kinds are ITML base types or "any";
+// alternatives are separated by '|'. Arguments past the end of args
+// accept the last listed kind.
+type signature struct {
+	returns string
+	args    []string
+}
+
+func sig(returns string, args ...string) signature {
+	return signature{returns: returns, args: args}
+}
+
+// arg returns the kinds accepted by argument i
+func (s signature) arg(i int) string {
+	if len(s.args) == 0 {
+		return "any"
+	}
+	if i >= len(s.args) {
+		i = len(s.args) - 1
+	}
+	return s.args[i]
+}
+
+// functions holds the built-ins available to expressions; forms holds
+// the implementation of those whose fn is nil
+var (
+	functions map[string]function
+	forms     map[string]form
+)
+
+func init() {
+	functions = map[string]function{
+		"length":     {1, 1, sig("integer", "string|array|object"), fnLength},
+		"upper":      {1, 1, sig("string", "string"), stringFn(strings.ToUpper)},
+		"lower":      {1, 1, sig("string", "string"), stringFn(strings.ToLower)},
+		"trim":       {1, 1, sig("string", "string"), stringFn(strings.TrimSpace)},
+		"substring":  {2, 3, sig("string", "string", "integer", "integer"), fnSubstring},
+		"truncate":   {2, 2, sig("string", "string", "integer"), fnTruncate},
+		"replace":    {3, 3, sig("string", "string", "string", "string"), fnReplace},
+		"split":      {2, 2, sig("array", "string", "string"), fnSplit},
+		"join":       {1, 2, sig("string", "array", "string"), fnJoin},
+		"contains":   {2, 2, sig("boolean", "string|array|object", "any"), fnContains},
+		"startsWith": {2, 2, sig("boolean", "string", "string"), fnStartsWith},
+		"endsWith":   {2, 2, sig("boolean", "string", "string"), fnEndsWith},
+		"default":    {2, 2, sig("any", "any", "any"), fnDefault},
+		"first":      {1, 1, sig("any", "array"), fnFirst},
+		"last":       {1, 1, sig("any", "array"), fnLast},
+		"reverse":    {1, 1, sig(sameKind, "string|array"), fnReverse},
+		"keys":       {1, 1, sig("array", "object"), fnKeys},
+		"round":      {1, 2, sig("number", "number", "integer"), fnRound},
+		"abs":        {1, 1, sig(sameKind, "number"), numberFn(math.Abs)},
+		"floor":      {1, 1, sig(sameKind, "number"), numberFn(math.Floor)},
+		"ceil":       {1, 1, sig(sameKind, "number"), numberFn(math.Ceil)},
+		"min":        {1, -1, sig("number", "any"), fnMin},
+		"max":        {1, -1, sig("number", "any"), fnMax},
+		"string":     {1, 1, sig("string", "any"), fnString},
+		"number":     {1, 1, sig("number", "string|number|boolean"), fnNumber},
+		"json":       {1, 1, sig("string", "any"), fnJSON},
+		"count":      {1, 2, sig("integer", "string|array|object", "any"), nil},
+		"map":        {2, 2, sig("array", "array", "any"), nil},
+		"filter":     {2, 2, sig("array", "array", "any"), nil},
+		"transform":  {2, 2, sig(lastKind, "any", "any"), nil},
+	}
+
+	forms = map[string]form{
+		"count":     formCount,
+		"map":       formMap,
+		"filter":    formFilter,
+		"transform": formTransform,
+	}
+
+	// JavaScript-style spellings, so `text.toUpperCase()` reads naturally
+	functions["len"] = functions["length"]
+	functions["toUpperCase"] = functions["upper"]
+	functions["toLowerCase"] = functions["lower"]
+	functions["includes"] = functions["contains"]
+}
+
+func callFunction(e parser.Expr, name string, args []interface{}) (interface{}, error) {
+	f, err := lookupFunction(e, name, len(args))
+	if err != nil {
+		return nil, err
+	}
+	result, err := f.fn(args)
+	return result, functionError(e, name, err)
+}
+
+// lookupFunction returns the built-in name after checking that it accepts
+// n arguments
+func lookupFunction(e parser.Expr, name string, n int) (function, error) {
+	f, ok := functions[name]
+	if !ok {
+		return f, evalErrorf(e, "unknown function '%s'", name)
+	}
+	if n < f.minArgs || (f.maxArgs >= 0 && n > f.maxArgs) {
+		return f, evalErrorf(e, "%s expects %s, got %d", name, arity(f), n)
+	}
+	return f, nil
+}
+
+// functionError prefixes errors from a built-in with the call expression
+func functionError(e parser.Expr, name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*EvalError); !ok {
+		err = evalErrorf(e, "%s: %v", name, err)
+	}
+	return err
+}
+
+func arity(f function) string {
+	switch {
+	case f.maxArgs < 0:
+		return "at least " + plural(f.minArgs)
+	case f.minArgs == f.maxArgs:
+		return plural(f.minArgs)
+	default:
+		return strconv.Itoa(f.minArgs) + " to " + plural(f.maxArgs)
+	}
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return strconv.Itoa(n) + " arguments"
+}
+
+// argError is returned by built-ins for bad arguments; callFunction
+// prefixes it with the offending expression
+type argError string
+
+func (e argError) Error() string { return string(e) }
+
+func wrongType(what string, v interface{}) error {
+	return argError("expected " + what + ", got " + typeName(v))
+}
+
+func stringArg(v interface{}) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", wrongType("string", v)
+	}
+	return s, nil
+}
+
+func intArg(v interface{}) (int, error) {
+	f, ok := toNumber(v)
+	if !ok || f != math.Trunc(f) {
+		return 0, wrongType("integer", v)
+	}
+	return int(f), nil
+}
+
+func stringFn(f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		s, err := stringArg(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(s), nil
+	}
+}
+
+func numberFn(f func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		switch n := args[0].(type) {
+		case int64:
+			return int64(f(float64(n))), nil
+		case float64:
+			return f(n), nil
+		}
+		return nil, wrongType("number", args[0])
+	}
+}
+
+func fnLength(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case map[string]interface{}:
+		return int64(len(v)), nil
+	case nil:
+		return int64(0), nil
+	}
+	return nil, wrongType("string, array or object", args[0])
+}
+
+// fnSubstring returns runes [start, end) clamped to the string, like
+// JavaScript's String.prototype.substring
+func fnSubstring(args []interface{}) (interface{}, error) {
+	s, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	start, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	end := len(runes)
+	if len(args) == 3 {
+		if end, err = intArg(args[2]); err != nil {
+			return nil, err
+		}
+	}
+	start, end = clamp(start, len(runes)), clamp(end, len(runes))
+	if start > end {
+		start, end = end, start
+	}
+	return string(runes[start:end]), nil
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > n {
+		return n
+	}
+	return i
+}
+
+// fnTruncate shortens s to at most n runes, ending in "..." when cut
+func fnTruncate(args []interface{}) (interface{}, error) {
+	s, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	n, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s, nil
+	}
+	if n <= 3 {
+		return string(runes[:n]), nil
+	}
+	return string(runes[:n-3]) + "...", nil
+}
+
+func fnReplace(args []interface{}) (interface{}, error) {
+	var strs [3]string
+	for i := range strs {
+		s, err := stringArg(args[i])
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+func fnSplit(args []interface{}) (interface{}, error) {
+	s, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(s, sep)
+	result := make([]interface{}, len(parts))
+	for i, p := range parts {
+		result[i] = p
+	}
+	return result, nil
+}
+
+func fnJoin(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, wrongType("array", args[0])
+	}
+	sep := ","
+	if len(args) == 2 {
+		var err error
+		if sep, err = stringArg(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	parts := make([]string, len(arr))
+	for i, v := range arr {
+		parts[i] = formatValue(v)
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func fnContains(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		sub, err := stringArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return strings.Contains(v, sub), nil
+	case []interface{}:
+		for _, el := range v {
+			if equalValues(el, args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, err := stringArg(args[1])
+		if err != nil {
+			return nil, err
+		}
+		_, ok := v[key]
+		return ok, nil
+	}
+	return nil, wrongType("string, array or object", args[0])
+}
+
+func fnStartsWith(args []interface{}) (interface{}, error) {
+	s, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(s, prefix), nil
+}
+
+func fnEndsWith(args []interface{}) (interface{}, error) {
+	s, err := stringArg(args[0])
+	if err != nil {
+		return nil, err
+	}
+	suffix, err := stringArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(s, suffix), nil
+}
+
+// fnDefault returns the fallback when the value is null or empty
+func fnDefault(args []interface{}) (interface{}, error) {
+	if s, ok := args[0].(string); args[0] == nil || (ok && s == "") {
+		return args[1], nil
+	}
+	return args[0], nil
+}
+
+func fnFirst(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, wrongType("array", args[0])
+	}
+	if len(arr) == 0 {
+		return nil, nil
+	}
+	return arr[0], nil
+}
+
+func fnLast(args []interface{}) (interface{}, error) {
+	arr, ok := args[0].([]interface{})
+	if !ok {
+		return nil, wrongType("array", args[0])
+	}
+	if len(arr) == 0 {
+		return nil, nil
+	}
+	return arr[len(arr)-1], nil
+}
+
+func fnReverse(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case string:
+		runes := []rune(v)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, el := range v {
+			result[len(v)-1-i] = el
+		}
+		return result, nil
+	}
+	return nil, wrongType("string or array", args[0])
+}
+
+// fnKeys returns an object's keys in sorted order
+func fnKeys(args []interface{}) (interface{}, error) {
+	obj, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, wrongType("object", args[0])
+	}
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	result := make([]interface{}, len(keys))
+	for i, k := range keys {
+		result[i] = k
+	}
+	return result, nil
+}
+
+func fnRound(args []interface{}) (interface{}, error) {
+	f, ok := toNumber(args[0])
+	if !ok {
+		return nil, wrongType("number", args[0])
+	}
+	if len(args) == 1 {
+		return int64(math.Round(f)), nil
+	}
+	digits, err := intArg(args[1])
+	if err != nil {
+		return nil, err
+	}
+	scale := math.Pow(10, float64(digits))
+	return math.Round(f*scale) / scale, nil
+}
+
+func fnMin(args []interface{}) (interface{}, error) {
+	return extreme(args, -1)
+}
+
+func fnMax(args []interface{}) (interface{}, error) {
+	return extreme(args, 1)
+}
+
+// extreme returns the smallest (sign -1) or largest (sign 1) number among
+// args, or among the elements of a single array argument
+func extreme(args []interface{}, sign int) (interface{}, error) {
+	if len(args) == 1 {
+		arr, ok := args[0].([]interface{})
+		if !ok {
+			return nil, wrongType("array", args[0])
+		}
+		args = arr
+	}
+	var best interface{}
+	var bestF float64
+	for _, v := range args {
+		f, ok := toNumber(v)
+		if !ok {
+			return nil, wrongType("number", v)
+		}
+		if best == nil || (sign < 0 && f < bestF) || (sign > 0 && f > bestF) {
+			best, bestF = v, f
+		}
+	}
+	return best, nil
+}
+
+func fnString(args []interface{}) (interface{}, error) {
+	return formatValue(args[0]), nil
+}
+
+func fnNumber(args []interface{}) (interface{}, error) {
+	switch v := args[0].(type) {
+	case int64, float64:
+		return v, nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+		return nil, argError("cannot convert " + strconv.Quote(v) + " to a number")
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, wrongType("string, number or boolean", args[0])
+}
+
+func fnJSON(args []interface{}) (interface{}, error) {
+	b, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// formCount counts the elements of an array, the keys of an object or
+// the runes of a string. With a condition it counts the array elements
+// for which the condition is truthy.
+func formCount(ev *evaluator, x interface{}, args []parser.Expr) (interface{}, error) {
+	if len(args) == 0 {
+		return fnLength([]interface{}{x})
+	}
+	matches, err := formFilter(ev, x, args)
+	if err != nil {
+		return nil, err
+	}
+	return int64(len(matches.([]interface{}))), nil
+}
+
+// formMap evaluates an expression for every element of an array
+func formMap(ev *evaluator, x interface{}, args []parser.Expr) (interface{}, error) {
+	arr, ok := x.([]interface{})
+	if !ok {
+		return nil, wrongType("array", x)
+	}
+	result := make([]interface{}, len(arr))
+	for i, el := range arr {
+		v, err := ev.with(el).eval(args[0])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// formFilter keeps the elements of an array for which a condition is truthy
+func formFilter(ev *evaluator, x interface{}, args []parser.Expr) (interface{}, error) {
+	arr, ok := x.([]interface{})
+	if !ok {
+		return nil, wrongType("array", x)
+	}
+	result := []interface{}{}
+	for _, el := range arr {
+		v, err := ev.with(el).eval(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if truthy(v) {
+			result = append(result, el)
+		}
+	}
+	return result, nil
+}
+
+// formTransform evaluates a template, usually an object literal, with
+// selectors such as `.main.temp` resolved against the value
+func formTransform(ev *evaluator, x interface{}, args []parser.Expr) (interface{}, error) {
+	return ev.with(x).eval(args[0])
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// maxRedirects bounds the redirects an http step follows
+const maxRedirects = 10
+
+// httpStep returns the handler for `http.get(url[, headers])` or
+// `http.post(url, body[, headers])`, which run under the package's network
+// policy. JSON responses are decoded; anything else is returned as text.
+func httpStep(method string) func(call *StepCall) (interface{}, error) {
+	return func(call *StepCall) (interface{}, error) {
+		args := call.Args
+		rawURL := args[0].(string)
+		var body interface{}
+		if method == http.MethodPost {
+			body, args = args[1], args[1:]
+		}
+		var headers map[string]interface{}
+		if len(args) > 1 {
+			headers, _ = args[1].(map[string]interface{})
+		}
+		return doRequest(call.Context, call.Network, call.Cassette, method, rawURL, body, headers)
+	}
+}
+
+// doRequest sends one request, checking the policy before it is sent and
+// again for every redirect. A nil policy allows any host. The request goes
+// through the cassette, if any, to be recorded or replayed.
+func doRequest(ctx context.Context, policy *pack.NetworkPolicy, cassette *Cassette, method, rawURL string, body interface{}, headers map[string]interface{}) (interface{}, error) {
+	limit, timeout := int64(pack.DefaultMaxBodySize), pack.DefaultNetworkTimeout
+	if policy != nil {
+		limit, timeout = policy.MaxBodySize, policy.Timeout
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if err := checkURL(policy, method, u); err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	contentType := ""
+	if method == http.MethodPost {
+		data, ct, err := encodeBody(body)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > limit {
+			return nil, &pack.PolicyViolation{
+				Policy: "network.outbound.maxBodySize",
+				Reason: fmt.Sprintf("request body of %d bytes exceeds the limit of %d", len(data), limit),
+			}
+		}
+		reader, contentType = bytes.NewReader(data), ct
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, formatValue(value))
+	}
+
+	client := &http.Client{
+		Transport: cassette.Transport(limit),
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			// A POST answered with 301, 302 or 303 is followed as a GET
+			return checkURL(policy, req.Method, req.URL)
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, rawURL, err)
+	}
+	if int64(len(data)) > limit {
+		return nil, &pack.PolicyViolation{
+			Policy: "network.outbound.maxBodySize",
+			Reason: fmt.Sprintf("response from %s exceeds the limit of %d bytes", u.Host, limit),
+		}
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s %s: %s", method, rawURL, resp.Status)
+	}
+
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return nil, fmt.Errorf("%s %s: invalid JSON response: %w", method, rawURL, err)
+		}
+		return value, nil
+	}
+	return string(data), nil
+}
+
+// encodeBody sends strings as text and every other value as JSON
+func encodeBody(body interface{}) ([]byte, string, error) {
+	if s, ok := body.(string); ok {
+		return []byte(s), "text/plain; charset=utf-8", nil
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot encode request body: %w", err)
+	}
+	return data, "application/json", nil
+}
+
+// checkURL reports whether a method request to u may be sent under the
+// policy. A nil policy allows any http or https URL.
+func checkURL(policy *pack.NetworkPolicy, method string, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if policy == nil {
+		return nil
+	}
+	return policy.CheckRequest(method, hostPort(u))
+}
+
+// hostPort returns the URL's host with the scheme's default port filled in
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func httpIntent(script string) *parser.Intent {
+	return &parser.Intent{
+		Name:       "http",
+		Parameters: []parser.Parameter{{Name: "url", Type: "string", Required: true}},
+		Script:     script,
+	}
+}
+
+// policyFor allows exactly the host of rawURL
+func policyFor(t *testing.T, rawURL string) *pack.NetworkPolicy {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pack.NetworkPolicy{
+		Allow:       []string{u.Host},
+		Deny:        []string{"*"},
+		MaxBodySize: pack.DefaultMaxBodySize,
+		Timeout:     pack.DefaultNetworkTimeout,
+	}
+}
+
+func TestExecute_HTTPGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"main": {"temp": 21.5}, "weather": [{"main": "Clouds"}]}`))
+	}))
+	defer server.Close()
+
+	script := `→ http.get("{url}/forecast", { "X-Token": "secret" })
+→ transform(result, { temperature: .main.temp, condition: .weather[0].main })
+→ return(result)`
+	result, err := ExecuteWithOptions(httpIntent(script), map[string]string{"url": server.URL}, Options{
+		Network: policyFor(t, server.URL),
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["temperature"] != 21.5 || result["condition"] != "Clouds" {
+		t.Errorf("Unexpected results: %v", result)
+	}
+}
+
+func TestExecute_HTTPPost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, "hello "+body["name"].(string))
+	}))
+	defer server.Close()
+
+	result, err := Execute(httpIntent(`→ http.post(url, { name: "Ann" })`), map[string]string{"url": server.URL}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["result"] != "hello Ann" {
+		t.Errorf("Expected text response, got %v", result)
+	}
+}
+
+func TestExecute_HTTPPolicyViolations(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.Write([]byte("ok"))
+		}
+	})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(other.URL, http.StatusFound))
+	defer redirect.Close()
+
+	tests := []struct {
+		name   string
+		script string
+		url    string
+		policy func(*pack.NetworkPolicy)
+		want   string
+	}{
+		{"denied host", `→ http.get(url)`, other.URL, nil, "is not allowed"},
+		{"nothing allowed", `→ http.get(url)`, server.URL, func(p *pack.NetworkPolicy) { p.Allow = nil }, "is not allowed"},
+		{"more specific deny", `→ http.get(url)`, server.URL, func(p *pack.NetworkPolicy) { p.Allow = []string{"*"}; p.Deny = []string{"127.0.0.*"} }, "is not allowed"},
+		{"method", `→ http.post(url, "x")`, server.URL, func(p *pack.NetworkPolicy) { p.Methods = []string{"GET"} }, "method POST is not allowed"},
+		{"request body", `→ http.post(url, "too long")`, server.URL, func(p *pack.NetworkPolicy) { p.MaxBodySize = 4 }, "request body of 8 bytes"},
+		{"response body", `→ http.get(url + "/large")`, server.URL, func(p *pack.NetworkPolicy) { p.MaxBodySize = 10 }, "exceeds the limit of 10 bytes"},
+		{"redirect", `→ http.get(url)`, redirect.URL, nil, "is not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := policyFor(t, server.URL)
+			if tt.url == redirect.URL {
+				policy = policyFor(t, redirect.URL)
+			}
+			if tt.policy != nil {
+				tt.policy(policy)
+			}
+			_, err := ExecuteWithOptions(httpIntent(tt.script), map[string]string{"url": tt.url}, Options{Network: policy})
+			var violation *pack.PolicyViolation
+			if !errors.As(err, &violation) {
+				t.Fatalf("Expected a policy violation, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		policy := policyFor(t, server.URL)
+		policy.Timeout = 50 * time.Millisecond
+		_, err := ExecuteWithOptions(httpIntent(`→ http.get(url + "/slow")`), map[string]string{"url": server.URL}, Options{Network: policy})
+		if err == nil || !strings.Contains(err.Error(), "Timeout") {
+			t.Errorf("Expected a timeout error, got %v", err)
+		}
+	})
+}
+
+func TestExecute_HTTPRedirectMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/found":
+			http.Redirect(w, r, "/done", http.StatusFound)
+		case "/temporary":
+			http.Redirect(w, r, "/done", http.StatusTemporaryRedirect)
+		default:
+			w.Write([]byte(r.Method))
+		}
+	}))
+	defer server.Close()
+
+	policy := policyFor(t, server.URL)
+	policy.Methods = []string{"POST"}
+
+	// 307 keeps the method, so the redirect is still a POST
+	result, err := ExecuteWithOptions(httpIntent(`→ http.post(url + "/temporary", "x")`), map[string]string{"url": server.URL}, Options{Network: policy})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["result"] != "POST" {
+		t.Errorf("Expected the redirect to be a POST, got %v", result)
+	}
+
+	// 302 is followed as a GET, which the policy does not allow
+	_, err = ExecuteWithOptions(httpIntent(`→ http.post(url + "/found", "x")`), map[string]string{"url": server.URL}, Options{Network: policy})
+	var violation *pack.PolicyViolation
+	if !errors.As(err, &violation) || !strings.Contains(err.Error(), "method GET is not allowed") {
+		t.Errorf("Expected the redirected GET to violate the policy, got %v", err)
+	}
+}
+
+func TestExecuteContext_HTTPCanceled(t *testing.T) {
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(aborted)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := ExecuteContext(ctx, httpIntent(`→ http.get(url)`), map[string]string{"url": server.URL}, Options{})
+	if !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("Expected the request to be aborted")
+	}
+}
+
+func TestNetworkPolicyFromManifest(t *testing.T) {
+	var manifest pack.ItpkgManifest
+	err := json.Unmarshal([]byte(`{"policies": {"security": {"network": {"outbound": {
+		"allow": ["api.example.com:443", "*.example.org"],
+		"deny": ["*"],
+		"methods": ["GET"],
+		"maxBodySize": 1024,
+		"timeout": "5s"
+	}}}}}`), &manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := manifest.NetworkPolicy()
+	if err != nil {
+		t.Fatalf("NetworkPolicy failed: %v", err)
+	}
+	if policy.MaxBodySize != 1024 || policy.Timeout != 5*time.Second {
+		t.Errorf("Unexpected limits: %+v", policy)
+	}
+
+	tests := []struct {
+		method string
+		host   string
+		ok     bool
+	}{
+		{"GET", "api.example.com:443", true},
+		{"GET", "api.example.com:80", false},
+		{"GET", "cdn.example.org:443", true},
+		{"GET", "example.net:443", false},
+		{"POST", "api.example.com:443", false},
+	}
+	for _, tt := range tests {
+		if err := policy.CheckRequest(tt.method, tt.host); (err == nil) != tt.ok {
+			t.Errorf("CheckRequest(%s, %s) = %v, want allowed=%v", tt.method, tt.host, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// workerStderrLimit bounds what a script worker's stderr keeps for error
+// messages; the worker only writes there when it crashes
+const workerStderrLimit = 64 << 10
+
+// executeJavaScript runs a `javascript:` script with the embedded engine,
+// in a worker process of its own so that its CPU time and memory can be
+// bounded. The script is the body of a function receiving the typed
+// inputs as `input`; its return value becomes the results. Scripts have
+// no access to the network or filesystem beyond the globals granted by
+// the package's capabilities:
+//
+//	http.outbound  http.get(url[, headers]), http.post(url, body[, headers])
+//	fs.read        fs.readFile(path)
+//	fs.write       fs.writeFile(path, text)
+//
+// The worker passes calls of those globals back to be made here, under
+// the package's policies. The script policy limits how long the script
+// runs, the CPU time it uses and how much memory it allocates.
+func executeJavaScript(code string, ctx *ExecutionContext) (ExecuteResult, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("javascript: cannot start the script worker: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx.Context, ctx.Scripts.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(runCtx, self)
+	cmd.Env = []string{workerEnv + "=1"}
+	if root, ok := os.LookupEnv("SYSTEMROOT"); ok {
+		cmd.Env = append(cmd.Env, "SYSTEMROOT="+root)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &cappedBuffer{limit: workerStderrLimit, overflow: func() {}}
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("javascript: cannot start the script worker: %w", err)
+	}
+
+	job := scriptJob{Code: code, Input: ctx.Values, CPUTime: ctx.Scripts.CPUTime, MaxMemory: ctx.Scripts.MaxMemory}
+	for _, capability := range []string{"http.outbound", "fs.read", "fs.write"} {
+		if hasCapability(ctx.Capabilities, capability) {
+			job.Globals = append(job.Globals, capability)
+		}
+	}
+	outcome, serveErr := serveWorker(runCtx, ctx, job, stdin, stdout)
+	stdin.Close()
+	waitErr := cmd.Wait()
+
+	switch {
+	case ctx.Context.Err() != nil:
+		return nil, ctx.Context.Err()
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return nil, &pack.PolicyViolation{
+			Policy: "scripts.timeout",
+			Reason: fmt.Sprintf("script ran longer than %s", ctx.Scripts.Timeout),
+		}
+	case outcome == nil:
+		err := serveErr
+		if waitErr != nil {
+			err = waitErr
+		}
+		if msg := bytes.TrimSpace(stderr.Bytes()); len(msg) > 0 {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("javascript: script worker failed: %w", err)
+	case outcome.Violation != nil:
+		return nil, outcome.Violation
+	case outcome.Error != "":
+		return nil, fmt.Errorf("javascript: %s", outcome.Error)
+	}
+	return scriptResults(cachedValue(outcome.Result), ctx), nil
+}
+
+// serveWorker sends the worker its job and makes the calls it passes back
+// until it reports the script's outcome
+func serveWorker(runCtx context.Context, ctx *ExecutionContext, job scriptJob, stdin io.Writer, stdout io.Reader) (*workerMessage, error) {
+	enc := json.NewEncoder(stdin)
+	if err := enc.Encode(job); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(stdout)
+	dec.UseNumber()
+	log := ctx.secrets.writer(logWriter)
+	for {
+		var msg workerMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil, err
+		}
+		switch msg.Type {
+		case workerLog:
+			fmt.Fprintln(log, msg.Text)
+		case workerCall:
+			var reply workerReply
+			value, err := callGlobal(runCtx, ctx, msg.Call, cachedValue(msg.Args).([]interface{}))
+			if err != nil {
+				reply.Error = err.Error()
+			} else {
+				reply.Value = value
+			}
+			if err := enc.Encode(reply); err != nil {
+				return nil, err
+			}
+		case workerDone:
+			return &msg, nil
+		default:
+			return nil, fmt.Errorf("unexpected message %q", msg.Type)
+		}
+	}
+}
+
+// callGlobal makes a call of a granted global for a script. Every call
+// uses runCtx, so it ends with the script.
+func callGlobal(runCtx context.Context, ctx *ExecutionContext, name string, args []interface{}) (interface{}, error) {
+	capability := map[string]string{
+		"http.get":     "http.outbound",
+		"http.post":    "http.outbound",
+		"fs.readFile":  "fs.read",
+		"fs.writeFile": "fs.write",
+	}[name]
+	if capability == "" || !hasCapability(ctx.Capabilities, capability) {
+		return nil, fmt.Errorf("%s is not available", name)
+	}
+	arg := func(i int) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		return nil
+	}
+
+	if capability == "http.outbound" {
+		method, rest := http.MethodGet, args
+		var body interface{}
+		if name == "http.post" {
+			method, body = http.MethodPost, arg(1)
+			if len(rest) > 1 {
+				rest = rest[1:]
+			}
+		}
+		var headers map[string]interface{}
+		if len(rest) > 1 {
+			headers, _ = rest[1].(map[string]interface{})
+		}
+		return doRequest(runCtx, ctx.Network, ctx.Cassette, method, formatValue(arg(0)), body, headers)
+	}
+	path, err := scriptPath(ctx.PackageDir, formatValue(arg(0)))
+	if err != nil {
+		return nil, err
+	}
+	if name == "fs.readFile" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return nil, os.WriteFile(path, []byte(formatValue(arg(1))), 0644)
+}
+
+// scriptPath resolves a path given to fs.readFile or fs.writeFile against
+// dir, or the working directory when dir is empty. Absolute paths and
+// paths leading out of dir are refused, so a script cannot reach files
+// such as the secret store.
+func scriptPath(dir, name string) (string, error) {
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		dir = wd
+	}
+	path := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, path)
+	if filepath.IsAbs(name) || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path outside the package directory: %s", name)
+	}
+	return path, nil
+}
+
+// hasCapability reports whether want is explicitly granted. Unlike
+// workflow steps, scripts get nothing from a nil grant list, so an intent
+// outside a package runs its scripts without filesystem, network or
+// process access.
+func hasCapability(granted []string, want string) bool {
+	for _, have := range granted {
+		if have == want {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptResults maps a script's return value onto the results. The keys
+// of a returned object become outputs; any other value is stored under
+// resultName.
+func scriptResults(value interface{}, ctx *ExecutionContext) ExecuteResult {
+	results := make(ExecuteResult)
+	if obj, ok := value.(map[string]interface{}); ok {
+		for key, v := range obj {
+			results[key] = v
+		}
+	} else if value != nil {
+		results[resultName(ctx.Intent)] = value
+	}
+	if results["status"] == nil {
+		results["status"] = "success"
+	}
+	return results
+}
@@ -0,0 +1,209 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func scriptIntent(script string, outputs ...parser.Output) *parser.Intent {
+	intent := workflowIntent("javascript:" + script)
+	intent.Outputs = outputs
+	return intent
+}
+
+func TestExecute_JavaScript(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		outputs []parser.Output
+		want    ExecuteResult
+	}{
+		{
+			name: "typed inputs and object result",
+			script: `
+				const words = input.text.split(" ");
+				return { count: words.length, over: words.length > input.limit, first: words[0].toUpperCase() };`,
+			want: ExecuteResult{"status": "success", "count": int64(3), "over": true, "first": "ONE"},
+		},
+		{
+			name:    "single declared output",
+			script:  `return input.text.length * 2;`,
+			outputs: []parser.Output{{Name: "doubled", Type: "integer"}},
+			want:    ExecuteResult{"status": "success", "doubled": int64(26)},
+		},
+		{
+			name:   "status from script",
+			script: `console.log("working"); return { status: "partial", items: [1, 2.5, "x"], nested: JSON.parse('{"a": null}') };`,
+			want:   ExecuteResult{"status": "partial", "items": []interface{}{int64(1), 2.5, "x"}, "nested": map[string]interface{}{"a": nil}},
+		},
+		{
+			name:   "no return value",
+			script: `let x = 1;`,
+			want:   ExecuteResult{"status": "success"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Execute(scriptIntent(tt.script, tt.outputs...), map[string]string{"text": "one two three"}, "")
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestExecute_JavaScriptErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   Options
+		want   string
+	}{
+		{"syntax error", `return {`, Options{}, "javascript: SyntaxError: script.js"},
+		{"exception", `throw new Error("bad input " + input.text)`, Options{}, "Error: bad input x"},
+		{"time limit", `while (true) {}`, Options{Scripts: &pack.ScriptPolicy{Timeout: 50 * time.Millisecond, MaxMemory: pack.DefaultScriptMaxMemory}}, "policy violation (scripts.timeout): script ran longer than 50ms"},
+		{"memory limit", `const a = []; while (true) { a.push("xxxxxxxxxxxxxxxx" + a.length); }`, Options{Scripts: &pack.ScriptPolicy{Timeout: 10 * time.Second, MaxMemory: 1 << 20}}, "policy violation (scripts.maxMemory)"},
+		{"cpu time limit", `while (true) {}`, Options{Scripts: &pack.ScriptPolicy{Timeout: 10 * time.Second, CPUTime: 100 * time.Millisecond, MaxMemory: pack.DefaultScriptMaxMemory}}, "policy violation (scripts.cpuTime): script used more than 100ms of CPU time"},
+		{"call stack", `function f() { return f(); } return f();`, Options{}, "javascript: maximum call stack size of 16384 calls exceeded"},
+		{"unrepresentable result", `return { ratio: 0 / 0 };`, Options{}, "javascript: cannot return the result"},
+		{"no network without capability", `return http.get("http://example.com")`, Options{Capabilities: []string{}}, "ReferenceError: http is not defined"},
+		{"no filesystem without capability", `return fs.readFile("/etc/hostname")`, Options{Capabilities: []string{"http.outbound"}}, "ReferenceError: fs is not defined"},
+		{"no network outside a package", `return http.get("http://example.com")`, Options{}, "ReferenceError: http is not defined"},
+		{"no filesystem outside a package", `return fs.readFile("/etc/hostname")`, Options{}, "ReferenceError: fs is not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExecuteWithOptions(scriptIntent(tt.script), map[string]string{"text": "x"}, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestExecute_JavaScriptCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"temp": 21.5}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(in, []byte("from disk"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out", "copy.txt")
+
+	intent := scriptIntent(`
+		const weather = http.get("` + server.URL + `");
+		fs.writeFile("out/copy.txt", fs.readFile("in.txt"));
+		return { temperature: weather.temp };`)
+	result, err := ExecuteWithOptions(intent, map[string]string{"text": "x"}, Options{
+		Network:      policyFor(t, server.URL),
+		Capabilities: []string{"http.outbound", "fs.read", "fs.write"},
+		PackageDir:   dir,
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["temperature"] != 21.5 {
+		t.Errorf("Expected temperature 21.5, got %v", result)
+	}
+	if data, err := os.ReadFile(out); err != nil || string(data) != "from disk" {
+		t.Errorf("Expected copied file, got %q (%v)", data, err)
+	}
+
+	// The package's network policy still applies
+	_, err = ExecuteWithOptions(scriptIntent(`return http.get("http://example.com")`), map[string]string{"text": "x"}, Options{
+		Network:      policyFor(t, server.URL),
+		Capabilities: []string{"http.outbound"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "host example.com:80 is not allowed") {
+		t.Errorf("Expected a network policy violation, got %v", err)
+	}
+
+	// Files outside the package directory are out of reach
+	for _, script := range []string{
+		`return fs.readFile("` + filepath.ToSlash(in) + `")`,
+		`return fs.readFile("../secrets.key")`,
+		`fs.writeFile("out/../../escape.txt", "x")`,
+	} {
+		_, err = ExecuteWithOptions(scriptIntent(script), map[string]string{"text": "x"}, Options{
+			Capabilities: []string{"fs.read", "fs.write"},
+			PackageDir:   filepath.Join(dir, "out"),
+		})
+		if err == nil || !strings.Contains(err.Error(), "path outside the package directory") {
+			t.Errorf("Expected %s to be refused, got %v", script, err)
+		}
+	}
+}
+
+func TestExecute_JavaScriptCPUTimeExcludesWaiting(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("slow"))
+	}))
+	defer server.Close()
+
+	policy := &pack.ScriptPolicy{Timeout: 10 * time.Second, CPUTime: 100 * time.Millisecond, MaxMemory: pack.DefaultScriptMaxMemory}
+	result, err := ExecuteWithOptions(scriptIntent(`return http.get("`+server.URL+`");`), map[string]string{"text": "x"}, Options{
+		Network:      policyFor(t, server.URL),
+		Capabilities: []string{"http.outbound"},
+		Scripts:      policy,
+	})
+	if err != nil {
+		t.Fatalf("Expected waiting not to count as CPU time, got %v", err)
+	}
+	if result["result"] != "slow" {
+		t.Errorf("Unexpected results: %v", result)
+	}
+}
+
+func TestExecuteContext_JavaScriptCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := ExecuteContext(ctx, scriptIntent(`while (true) {}`), map[string]string{"text": "x"}, Options{})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("Expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestScriptPolicyFromManifest(t *testing.T) {
+	manifest := &pack.ItpkgManifest{Policies: map[string]interface{}{
+		"security": map[string]interface{}{
+			"scripts": map[string]interface{}{"timeout": "2s", "cpuTime": "500ms", "maxMemory": float64(1024)},
+		},
+	}}
+	policy, err := manifest.ScriptPolicy()
+	if err != nil {
+		t.Fatalf("ScriptPolicy failed: %v", err)
+	}
+	if policy.Timeout != 2*time.Second || policy.CPUTime != 500*time.Millisecond || policy.MaxMemory != 1024 {
+		t.Errorf("Unexpected policy: %+v", policy)
+	}
+
+	defaults, err := (&pack.ItpkgManifest{}).ScriptPolicy()
+	if err != nil || !reflect.DeepEqual(defaults, pack.DefaultScriptPolicy()) {
+		t.Errorf("Expected default policy, got %+v (%v)", defaults, err)
+	}
+
+	manifest.Policies["security"].(map[string]interface{})["scripts"] = map[string]interface{}{"timeout": "soon"}
+	if _, err := manifest.ScriptPolicy(); err == nil || !strings.Contains(err.Error(), "policies.security.scripts.timeout") {
+		t.Errorf("Expected timeout error, got %v", err)
+	}
+}
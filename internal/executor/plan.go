@@ -0,0 +1,287 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// Plan describes what executing an intent would do. It is worked out
+// without running any step or script.
+type Plan struct {
+	Intent string                 `json:"intent"`
+	Runner string                 `json:"runner"` // workflow, javascript, python, exec:<interpreter>, template or none
+	Inputs map[string]interface{} `json:"inputs"`
+	Steps  []PlanStep             `json:"steps,omitempty"`
+	// Requests are the HTTP requests made by http steps
+	Requests []PlanRequest `json:"requests,omitempty"`
+	// Capabilities are those the execution would use; Missing lists the
+	// ones the package does not declare, which would make it fail
+	Capabilities []string        `json:"capabilities,omitempty"`
+	Missing      []string        `json:"missing,omitempty"`
+	Outputs      []parser.Output `json:"outputs,omitempty"`
+	Text         string          `json:"text,omitempty"` // the rendered text of a template script
+}
+
+// PlanStep is one workflow step, in source order. Steps nested in a block
+// have a greater Depth; Branch is "else" or "catch" for the steps of those
+// branches.
+type PlanStep struct {
+	ID      string    `json:"id"`
+	At      string    `json:"at"`
+	Depth   int       `json:"depth"`
+	Branch  string    `json:"branch,omitempty"`
+	Source  string    `json:"source"`
+	Call    string    `json:"call,omitempty"` // the registered step it calls
+	Args    []PlanArg `json:"args,omitempty"`
+	Returns string    `json:"returns"` // the kind of its result
+}
+
+// PlanArg is an argument of a step call. Arguments using only the inputs
+// are resolved; the others depend on earlier steps.
+type PlanArg struct {
+	Name     string      `json:"name"`
+	Expr     string      `json:"expr"`
+	Value    interface{} `json:"value,omitempty"`
+	Resolved bool        `json:"resolved"`
+}
+
+// PlanRequest is an HTTP request a step would send. URL is the resolved
+// URL, or the expression computing it when that depends on earlier steps.
+// Blocked explains why the network policy would refuse it.
+type PlanRequest struct {
+	Step     string `json:"step"`
+	At       string `json:"at"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Resolved bool   `json:"resolved"`
+	Blocked  string `json:"blocked,omitempty"`
+}
+
+// httpMethods maps the built-in http steps to the method they send
+var httpMethods = map[string]string{
+	"http.get":  http.MethodGet,
+	"http.post": http.MethodPost,
+}
+
+// PlanIntent works out what ExecuteContext would do with the same inputs
+// and options: the steps of a workflow with their arguments resolved from
+// the inputs and their result kinds, the HTTP requests and capabilities
+// they need, or the text of a template. Inputs are resolved and workflows
+// checked as for an execution, and errors are returned the same way;
+// missing capabilities and blocked requests are reported in the plan.
+func PlanIntent(intent *parser.Intent, inputParams map[string]string, opts Options) (*Plan, error) {
+	values, err := intent.ResolveParameters(inputParams)
+	if err != nil {
+		return nil, err
+	}
+	ctx := &ExecutionContext{
+		Context:      context.Background(),
+		Intent:       intent,
+		Inputs:       inputParams,
+		Values:       values,
+		Network:      opts.Network,
+		Capabilities: opts.Capabilities,
+		Steps:        opts.Steps,
+		Mock:         opts.Mock,
+	}
+	if ctx.Steps == nil {
+		ctx.Steps = DefaultRegistry
+	}
+
+	plan := &Plan{Intent: intent.Name, Inputs: values, Outputs: intent.Outputs}
+	script := intent.Script
+	var needs []string
+	switch {
+	case strings.Contains(script, "→"):
+		plan.Runner = "workflow"
+		if needs, err = planWorkflow(plan, ctx); err != nil {
+			return nil, err
+		}
+	case strings.HasPrefix(script, "javascript:"):
+		plan.Runner = "javascript"
+		needs = scriptCapabilities(script)
+	case strings.HasPrefix(script, "python:"):
+		plan.Runner = "python"
+		needs = []string{"process.exec"}
+	case strings.HasPrefix(script, "exec:"):
+		interpreter, _ := splitInterpreter(script[5:])
+		plan.Runner = "exec:" + interpreter
+		needs = []string{"process.exec"}
+	case script != "":
+		plan.Runner = "template"
+		results, err := executeTemplate(script, ctx)
+		if err != nil {
+			return nil, err
+		}
+		plan.Text, _ = results[resultName(intent)].(string)
+	default:
+		plan.Runner = "none"
+		if err := checkUnscripted(intent, opts.Mock); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(needs)
+	plan.Capabilities = needs
+	granted := opts.Capabilities
+	if granted == nil && plan.Runner == "javascript" {
+		// JavaScript globals need an explicit grant; outside a package,
+		// workflows and subprocess scripts are unrestricted
+		granted = []string{}
+	}
+	plan.Missing = missingCapabilities(StepSpec{Capabilities: needs}, granted)
+	redactPlan(plan, newSecretValues(intent, values, nil))
+	return plan, nil
+}
+
+// redactPlan masks secrets in the values a plan shows
+func redactPlan(plan *Plan, secrets secretValues) {
+	if len(secrets) == 0 {
+		return
+	}
+	plan.Inputs = secrets.redactValue(plan.Inputs).(map[string]interface{})
+	plan.Text = secrets.redact(plan.Text)
+	for i := range plan.Steps {
+		for j := range plan.Steps[i].Args {
+			arg := &plan.Steps[i].Args[j]
+			arg.Value = secrets.redactValue(arg.Value)
+		}
+	}
+	for i := range plan.Requests {
+		req := &plan.Requests[i]
+		req.URL, req.Blocked = secrets.redact(req.URL), secrets.redact(req.Blocked)
+	}
+}
+
+// scriptCapabilities returns the capabilities whose globals a javascript:
+// script refers to
+func scriptCapabilities(script string) []string {
+	var needs []string
+	if strings.Contains(script, "http.get(") || strings.Contains(script, "http.post(") {
+		needs = append(needs, "http.outbound")
+	}
+	if strings.Contains(script, "fs.readFile(") {
+		needs = append(needs, "fs.read")
+	}
+	if strings.Contains(script, "fs.writeFile(") {
+		needs = append(needs, "fs.write")
+	}
+	return needs
+}
+
+// planWorkflow checks the workflow and adds its steps and requests to the
+// plan, returning the capabilities its steps require. Capabilities are
+// not checked here, so that the plan can list all the missing ones.
+func planWorkflow(plan *Plan, ctx *ExecutionContext) ([]string, error) {
+	steps, err := parser.ParseWorkflow("workflow", ctx.Intent.Script)
+	if err != nil {
+		return nil, err
+	}
+	kinds, err := checkWorkflow(ctx.Intent, steps, Options{Steps: ctx.Steps})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &planner{plan: plan, ctx: ctx, scope: templateScope(ctx), kinds: kinds, needs: make(map[string]bool)}
+	p.block(steps, 0, "")
+	needs := make([]string, 0, len(p.needs))
+	for capability := range p.needs {
+		needs = append(needs, capability)
+	}
+	return needs, nil
+}
+
+// planner walks the steps of a workflow for PlanIntent. Its scope only
+// holds the inputs, so expressions using earlier results stay unresolved.
+type planner struct {
+	plan  *Plan
+	ctx   *ExecutionContext
+	scope map[string]interface{}
+	kinds map[*parser.Step]string
+	needs map[string]bool
+}
+
+// block adds steps to the plan, naming them as workflowRun.block does
+func (p *planner) block(steps []*parser.Step, depth int, branch string) {
+	for i, step := range steps {
+		id := step.Name
+		if id == "" && depth == 0 {
+			id = fmt.Sprintf("step%d", i+1)
+		}
+		if id == "" {
+			id = step.At.String()
+		}
+		p.step(step, id, depth, branch)
+	}
+}
+
+func (p *planner) step(step *parser.Step, id string, depth int, branch string) {
+	ps := PlanStep{
+		ID:      id,
+		At:      step.At.String(),
+		Depth:   depth,
+		Branch:  branch,
+		Source:  step.String(),
+		Returns: p.kinds[step],
+	}
+	if step.Kind == parser.StepExpr {
+		p.call(step, &ps)
+	}
+	p.plan.Steps = append(p.plan.Steps, ps)
+
+	p.block(step.Body, depth+1, "")
+	if step.Kind == parser.StepTry {
+		p.block(step.Else, depth+1, "catch")
+	} else {
+		p.block(step.Else, depth+1, "else")
+	}
+}
+
+// call fills in the registered step a plain step calls, with its
+// arguments, and notes its capabilities and HTTP request
+func (p *planner) call(step *parser.Step, ps *PlanStep) {
+	call, ok := step.Expr.(*parser.CallExpr)
+	if !ok {
+		return
+	}
+	name := stepName(call)
+	h, ok := p.ctx.Steps.Lookup(name)
+	if !ok {
+		return
+	}
+	spec := h.Spec()
+	ps.Call = name
+	for _, capability := range spec.Capabilities {
+		p.needs[capability] = true
+	}
+	for i, arg := range call.Args {
+		pa := PlanArg{Name: spec.Args[i].Name, Expr: arg.Value.String()}
+		if value, err := EvalExpr(arg.Value, p.scope); err == nil {
+			pa.Value, pa.Resolved = value, true
+		}
+		ps.Args = append(ps.Args, pa)
+	}
+
+	method, ok := httpMethods[name]
+	if !ok || len(ps.Args) == 0 {
+		return
+	}
+	req := PlanRequest{Step: ps.ID, At: ps.At, Method: method, URL: ps.Args[0].Expr}
+	if rawURL, ok := ps.Args[0].Value.(string); ok && ps.Args[0].Resolved {
+		req.URL, req.Resolved = rawURL, true
+		u, err := url.Parse(rawURL)
+		if err == nil {
+			err = checkURL(p.ctx.Network, method, u)
+		}
+		if err != nil {
+			req.Blocked = err.Error()
+		}
+	}
+	p.plan.Requests = append(p.plan.Requests, req)
+}
@@ -0,0 +1,143 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func TestPlanIntent_Workflow(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	script := `→ data = http.get("` + server.URL + `/items?q={text}")
+→ for item in data:
+    → http.post(item.url, { text: text })
+→ http.get("http://example.com/{text}")
+→ if limit > 1:
+    → log("many")
+else:
+    → upper(text)
+→ return(count=length(data))`
+	plan, err := PlanIntent(workflowIntent(script), map[string]string{"text": "tea"}, Options{
+		Network:      policyFor(t, server.URL),
+		Capabilities: []string{},
+	})
+	if err != nil {
+		t.Fatalf("PlanIntent failed: %v", err)
+	}
+	if hits != 0 {
+		t.Errorf("Expected no requests to be sent, got %d", hits)
+	}
+
+	type step struct {
+		id      string
+		depth   int
+		branch  string
+		call    string
+		returns string
+	}
+	var steps []step
+	for _, s := range plan.Steps {
+		steps = append(steps, step{s.ID, s.Depth, s.Branch, s.Call, s.Returns})
+	}
+	wantSteps := []step{
+		{"data", 0, "", "http.get", "any"},
+		{"step2", 0, "", "", "array"},
+		{"workflow:3:5", 1, "", "http.post", "any"},
+		{"step3", 0, "", "http.get", "any"},
+		{"step4", 0, "", "", "string"},
+		{"workflow:6:5", 1, "", "log", "string"},
+		{"workflow:8:5", 1, "else", "", "string"},
+		{"step5", 0, "", "", "null"},
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("Expected steps %v, got %v", wantSteps, steps)
+	}
+
+	wantArgs := []PlanArg{
+		{Name: "url", Expr: "item.url"},
+		{Name: "body", Expr: "{ text: text }", Value: map[string]interface{}{"text": "tea"}, Resolved: true},
+	}
+	if args := plan.Steps[2].Args; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Expected arguments %+v, got %+v", wantArgs, args)
+	}
+
+	wantRequests := []PlanRequest{
+		{Step: "data", At: "workflow:1:1", Method: "GET", URL: server.URL + "/items?q=tea", Resolved: true},
+		{Step: "workflow:3:5", At: "workflow:3:5", Method: "POST", URL: "item.url"},
+		{Step: "step3", At: "workflow:4:1", Method: "GET", URL: "http://example.com/tea", Resolved: true,
+			Blocked: "policy violation (network.outbound): host example.com:80 is not allowed"},
+	}
+	if !reflect.DeepEqual(plan.Requests, wantRequests) {
+		t.Errorf("Expected requests %+v, got %+v", wantRequests, plan.Requests)
+	}
+
+	if want := []string{"http.outbound"}; !reflect.DeepEqual(plan.Capabilities, want) || !reflect.DeepEqual(plan.Missing, want) {
+		t.Errorf("Expected http.outbound to be needed and missing, got %v and %v", plan.Capabilities, plan.Missing)
+	}
+}
+
+func TestPlanIntent_Runners(t *testing.T) {
+	tests := []struct {
+		name         string
+		script       string
+		outputs      []parser.Output
+		runner       string
+		capabilities []string
+		missing      []string
+		text         string
+	}{
+		{"template", "Hi {{text}}", []parser.Output{{Name: "greeting", Type: "string"}}, "template", nil, nil, "Hi tea"},
+		{"javascript", `javascript: fs.writeFile("x", http.get(input.text))`, nil, "javascript", []string{"fs.write", "http.outbound"}, []string{"fs.write", "http.outbound"}, ""},
+		{"python", "python:\nprint(1)", nil, "python", []string{"process.exec"}, nil, ""},
+		{"exec", "exec:ruby\nputs 1", nil, "exec:ruby", []string{"process.exec"}, nil, ""},
+		{"none", "", []parser.Output{{Name: "status", Type: "string"}}, "none", nil, nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			intent := workflowIntent(tt.script)
+			intent.Outputs = tt.outputs
+			plan, err := PlanIntent(intent, map[string]string{"text": "tea"}, Options{})
+			if err != nil {
+				t.Fatalf("PlanIntent failed: %v", err)
+			}
+			if plan.Runner != tt.runner || !reflect.DeepEqual(plan.Capabilities, tt.capabilities) || plan.Text != tt.text {
+				t.Errorf("Expected %s %v %q, got %s %v %q", tt.runner, tt.capabilities, tt.text, plan.Runner, plan.Capabilities, plan.Text)
+			}
+			// Outside a package, JavaScript is granted nothing and
+			// subprocesses are unrestricted
+			if !reflect.DeepEqual(plan.Missing, tt.missing) {
+				t.Errorf("Expected missing capabilities %v, got %v", tt.missing, plan.Missing)
+			}
+		})
+	}
+}
+
+func TestPlanIntent_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		intent *parser.Intent
+		inputs map[string]string
+		want   string
+	}{
+		{"type error", workflowIntent(`→ upper(limit)`), map[string]string{"text": "x"}, "argument 1 of upper must be string, got integer"},
+		{"invalid input", workflowIntent(`→ upper(text)`), map[string]string{"text": "x", "limit": "many"}, "invalid integer"},
+		{"no script", &parser.Intent{Name: "draft", Outputs: []parser.Output{{Name: "total", Type: "integer"}}}, nil, "no script to produce total"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PlanIntent(tt.intent, tt.inputs, Options{})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
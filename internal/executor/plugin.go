@@ -0,0 +1,148 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// pluginTimeout bounds a single run of a plugin binary
+const pluginTimeout = 60 * time.Second
+
+// pluginRequest is written as JSON to a plugin's stdin. Method is
+// "describe", answered with the steps the plugin provides, or "run",
+// answered with the result of one step call.
+type pluginRequest struct {
+	Method string                 `json:"method"`
+	Step   string                 `json:"step,omitempty"`
+	Args   []interface{}          `json:"args,omitempty"`
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// pluginResponse is read as JSON from a plugin's stdout
+type pluginResponse struct {
+	Steps  []StepSpec  `json:"steps,omitempty"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// LoadPlugin registers the steps provided by an external plugin binary.
+// The command is run once per request with a JSON request on stdin and
+// must print a JSON response on stdout:
+//
+//	{"method": "describe"}
+//	  → {"steps": [{"name": "db.lookup", "args": [{"name": "id", "type": "string"}], "returns": "object"}]}
+//	{"method": "run", "step": "db.lookup", "args": ["42"], "inputs": {...}}
+//	  → {"result": {...}} or {"error": "message"}
+//
+// It returns the names of the registered steps.
+func (r *Registry) LoadPlugin(command string, args ...string) ([]string, error) {
+	p := &plugin{command: command, args: args}
+	resp, err := p.request(context.Background(), &pluginRequest{Method: "describe"}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Steps) == 0 {
+		return nil, fmt.Errorf("plugin %s provides no steps", command)
+	}
+
+	names := make([]string, len(resp.Steps))
+	for i, spec := range resp.Steps {
+		if err := r.Register(&pluginStep{plugin: p, spec: spec}); err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", command, err)
+		}
+		names[i] = spec.Name
+	}
+	return names, nil
+}
+
+type plugin struct {
+	command string
+	args    []string
+}
+
+// request runs the plugin with req on stdin, killing it when ctx ends.
+// Its stderr goes to stderr when set, and is otherwise included in errors.
+func (p *plugin) request(ctx context.Context, req *pluginRequest, stderr io.Writer) (*pluginResponse, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: cannot encode request: %w", p.command, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	var stdout, errBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command, p.args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &errBuf
+	if stderr != nil {
+		cmd.Stderr = stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(errBuf.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("plugin %s: %w", p.command, err)
+	}
+
+	var resp pluginResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("plugin %s: invalid response: %w", p.command, err)
+	}
+	return &resp, nil
+}
+
+// pluginStep is a step implemented by a plugin binary
+type pluginStep struct {
+	plugin *plugin
+	spec   StepSpec
+}
+
+func (s *pluginStep) Spec() StepSpec { return s.spec }
+
+func (s *pluginStep) Run(call *StepCall) (interface{}, error) {
+	req := &pluginRequest{
+		Method: "run",
+		Step:   call.Step,
+		Args:   call.Args,
+		Inputs: pluginInputs(call),
+	}
+	resp, err := call.Cassette.plugin(s.plugin.command, req, func() (*pluginResponse, error) {
+		return s.plugin.request(call.Context, req, call.Log)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("%s: %s", call.Step, resp.Error)
+	}
+	return resp.Result, nil
+}
+
+// pluginInputs returns the intent's inputs without its secrets, which a
+// plugin only receives when the workflow passes them as arguments
+func pluginInputs(call *StepCall) map[string]interface{} {
+	if call.exec == nil {
+		return call.Inputs
+	}
+	secrets := call.exec.Intent.SecretParameters()
+	if len(secrets) == 0 {
+		return call.Inputs
+	}
+	inputs := make(map[string]interface{}, len(call.Inputs))
+	for name, value := range call.Inputs {
+		inputs[name] = value
+	}
+	for _, name := range secrets {
+		delete(inputs, name)
+	}
+	return inputs
+}
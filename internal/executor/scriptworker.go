@@ -0,0 +1,273 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// workerEnv is set in the environment of a process started to run one
+// javascript: script. Any program linking the executor serves as the
+// worker, so the CLI, tests and embedding programs need no separate binary.
+const workerEnv = "INTENT_SCRIPT_WORKER"
+
+// workerPollInterval is how often a worker checks its script's CPU time
+// and heap growth
+const workerPollInterval = 10 * time.Millisecond
+
+// workerMaxCallStack bounds the nesting of a script's function calls
+const workerMaxCallStack = 1 << 14
+
+// Types of workerMessage
+const (
+	workerLog  = "log"  // a console.log line
+	workerCall = "call" // a call of a granted global, answered by a workerReply
+	workerDone = "done" // the script's outcome; the last message
+)
+
+// scriptJob is the script a worker runs, read as JSON from its stdin
+type scriptJob struct {
+	Code      string                 `json:"code"`
+	Input     map[string]interface{} `json:"input"`
+	Globals   []string               `json:"globals,omitempty"` // capabilities whose globals are defined
+	CPUTime   time.Duration          `json:"cpuTime,omitempty"`
+	MaxMemory int64                  `json:"maxMemory"`
+}
+
+// workerMessage is written as JSON by a worker to its stdout
+type workerMessage struct {
+	Type      string                `json:"type"`
+	Text      string                `json:"text,omitempty"`
+	Call      string                `json:"call,omitempty"` // such as http.get
+	Args      []interface{}         `json:"args,omitempty"`
+	Result    interface{}           `json:"result,omitempty"`
+	Error     string                `json:"error,omitempty"` // an exception the script did not catch
+	Violation *pack.PolicyViolation `json:"violation,omitempty"`
+}
+
+// workerReply answers a call, written to the worker's stdin
+type workerReply struct {
+	Value interface{} `json:"value,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+func init() {
+	if os.Getenv(workerEnv) != "" {
+		os.Exit(runWorker(os.Stdin, os.Stdout))
+	}
+}
+
+// runWorker runs the job read from r, writing its messages to w, and
+// returns the process's exit status
+func runWorker(r io.Reader, w io.Writer) int {
+	in := json.NewDecoder(bufio.NewReader(r))
+	in.UseNumber()
+	var job scriptJob
+	if err := in.Decode(&job); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid job: %v\n", err)
+		return 2
+	}
+	out := json.NewEncoder(w)
+	var mu sync.Mutex // held while a message is written and its reply read
+	send := func(msg *workerMessage, reply *workerReply) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := out.Encode(msg); err != nil {
+			return err
+		}
+		if reply == nil {
+			return nil
+		}
+		return in.Decode(reply)
+	}
+
+	done := workerMessage{Type: workerDone}
+	result, err := runScript(&job, send)
+	var violation *pack.PolicyViolation
+	switch {
+	case errors.As(err, &violation):
+		done.Violation = violation
+	case err != nil:
+		done.Error = err.Error()
+	default:
+		done.Result = result
+	}
+	if err := send(&done, nil); err != nil {
+		// A result JSON cannot represent, such as NaN
+		done.Result, done.Error = nil, fmt.Sprintf("cannot return the result: %v", err)
+		if err := send(&done, nil); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// runScript runs the job's script in a new VM and returns its exported
+// value. Globals send their calls, and console.log its lines, with send.
+func runScript(job *scriptJob, send func(*workerMessage, *workerReply) error) (interface{}, error) {
+	program, err := goja.Compile("script.js", "(function (input) {"+job.Code+"\n})", false)
+	if err != nil {
+		return nil, err
+	}
+	vm := goja.New()
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+	vm.SetMaxCallStackSize(workerMaxCallStack)
+	if err := defineGlobals(vm, job, send); err != nil {
+		return nil, err
+	}
+
+	stop := watchScript(vm, job)
+	defer stop()
+
+	fnValue, err := vm.RunProgram(program)
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	fn, _ := goja.AssertFunction(fnValue)
+	value, err := fn(goja.Undefined(), vm.ToValue(cachedValue(job.Input)))
+	if err != nil {
+		return nil, scriptError(err)
+	}
+	return value.Export(), nil
+}
+
+// defineGlobals installs console and the globals the job grants
+func defineGlobals(vm *goja.Runtime, job *scriptJob, send func(*workerMessage, *workerReply) error) error {
+	// throw turns a Go error into a JavaScript exception
+	throw := func(err error) {
+		panic(vm.NewGoError(err))
+	}
+
+	console := vm.NewObject()
+	console.Set("log", func(call goja.FunctionCall) goja.Value {
+		parts := make([]string, len(call.Arguments))
+		for i, arg := range call.Arguments {
+			parts[i] = formatValue(arg.Export())
+		}
+		if err := send(&workerMessage{Type: workerLog, Text: strings.Join(parts, " ")}, nil); err != nil {
+			throw(err)
+		}
+		return goja.Undefined()
+	})
+	if err := vm.Set("console", console); err != nil {
+		return err
+	}
+
+	// global passes a call on to the executor. Paths, URLs and text are
+	// converted to strings as JavaScript would; bodies and headers are
+	// passed as values.
+	global := func(name string, values ...int) func(call goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			args := make([]interface{}, len(call.Arguments))
+			for i, arg := range call.Arguments {
+				args[i] = arg.String()
+				for _, v := range values {
+					if i == v {
+						args[i] = arg.Export()
+					}
+				}
+			}
+			var reply workerReply
+			if err := send(&workerMessage{Type: workerCall, Call: name, Args: args}, &reply); err != nil {
+				throw(err)
+			}
+			if reply.Error != "" {
+				throw(errors.New(reply.Error))
+			}
+			return vm.ToValue(cachedValue(reply.Value))
+		}
+	}
+
+	granted := make(map[string]bool)
+	for _, capability := range job.Globals {
+		granted[capability] = true
+	}
+	if granted["http.outbound"] {
+		client := vm.NewObject()
+		client.Set("get", global("http.get", 1))
+		client.Set("post", global("http.post", 1, 2))
+		if err := vm.Set("http", client); err != nil {
+			return err
+		}
+	}
+	fs := vm.NewObject()
+	if granted["fs.read"] {
+		fs.Set("readFile", global("fs.readFile"))
+	}
+	if granted["fs.write"] {
+		fs.Set("writeFile", global("fs.writeFile"))
+	}
+	if len(fs.Keys()) > 0 {
+		return vm.Set("fs", fs)
+	}
+	return nil
+}
+
+// watchScript interrupts the script when the worker's CPU time or heap
+// growth goes over the job's limits. Nothing else runs in the worker, so
+// both are the script's own. The returned function stops watching.
+func watchScript(vm *goja.Runtime, job *scriptJob) func() {
+	done := make(chan struct{})
+	go func() {
+		sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+		metrics.Read(sample)
+		base, baseCPU := sample[0].Value.Uint64(), processCPUTime()
+
+		ticker := time.NewTicker(workerPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if used := processCPUTime() - baseCPU; job.CPUTime > 0 && used > job.CPUTime {
+					vm.Interrupt(&pack.PolicyViolation{
+						Policy: "scripts.cpuTime",
+						Reason: fmt.Sprintf("script used more than %s of CPU time", job.CPUTime),
+					})
+					return
+				}
+				metrics.Read(sample)
+				if used := sample[0].Value.Uint64(); used > base && int64(used-base) > job.MaxMemory {
+					vm.Interrupt(&pack.PolicyViolation{
+						Policy: "scripts.maxMemory",
+						Reason: fmt.Sprintf("script allocated more than %d bytes", job.MaxMemory),
+					})
+					return
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// scriptError converts an exception or interruption into an error. A
+// script stopped by a limit returns the *pack.PolicyViolation.
+func scriptError(err error) error {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		if cause, ok := interrupted.Value().(error); ok {
+			return cause
+		}
+	}
+	var overflow *goja.StackOverflowError
+	if errors.As(err, &overflow) {
+		return fmt.Errorf("maximum call stack size of %d calls exceeded", workerMaxCallStack)
+	}
+	var exception *goja.Exception
+	if errors.As(err, &exception) {
+		return errors.New(exception.Error())
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package executor
+
+import (
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// secretMask replaces the value of a secret wherever it would be shown
+// or stored
+const secretMask = "***"
+
+// secretValues are the values of an execution's secret inputs, longest
+// first so that a secret containing another is masked whole
+type secretValues []string
+
+// newSecretValues returns the values of intent's secret parameters, along
+// with those of the executions calling it, which it may have been given
+func newSecretValues(intent *parser.Intent, values map[string]interface{}, inherited secretValues) secretValues {
+	secrets := append(secretValues(nil), inherited...)
+	for _, name := range intent.SecretParameters() {
+		if value, ok := values[name].(string); ok && value != "" {
+			secrets = append(secrets, value)
+		}
+	}
+	sort.SliceStable(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// redact masks every secret in text
+func (s secretValues) redact(text string) string {
+	for _, secret := range s {
+		text = strings.ReplaceAll(text, secret, secretMask)
+	}
+	return text
+}
+
+// redactValue returns a copy of v with every secret masked in its strings
+// and object keys. v itself is left alone, since others may hold it.
+func (s secretValues) redactValue(v interface{}) interface{} {
+	if len(s) == 0 {
+		return v
+	}
+	switch v := v.(type) {
+	case string:
+		return s.redact(v)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, elem := range v {
+			out[i] = s.redactValue(elem)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			out[s.redact(key)] = s.redactValue(elem)
+		}
+		return out
+	case ExecuteResult:
+		return ExecuteResult(s.redactValue(map[string]interface{}(v)).(map[string]interface{}))
+	}
+	return v
+}
+
+// redactResults returns a copy of results with every secret masked
+func (s secretValues) redactResults(results ExecuteResult) ExecuteResult {
+	if results == nil || len(s) == 0 {
+		return results
+	}
+	return s.redactValue(results).(ExecuteResult)
+}
+
+// redactError returns err with every secret masked in its message. The
+// errors it wraps can still be found with errors.Is and errors.As.
+func (s secretValues) redactError(err error) error {
+	if err == nil || len(s) == 0 {
+		return err
+	}
+	return &redactedError{err: err, secrets: s}
+}
+
+type redactedError struct {
+	err     error
+	secrets secretValues
+}
+
+func (e *redactedError) Error() string { return e.secrets.redact(e.err.Error()) }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// writer returns a writer masking every secret in what is written to w,
+// one write at a time
+func (s secretValues) writer(w io.Writer) io.Writer {
+	if len(s) == 0 {
+		return w
+	}
+	return &redactingWriter{w: w, secrets: s}
+}
+
+type redactingWriter struct {
+	w       io.Writer
+	secrets secretValues
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(w.w, w.secrets.redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,154 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+const testSecret = "tok-5ecret"
+
+// secretIntent is workflowIntent with a `token` secret input
+func secretIntent(script string) *parser.Intent {
+	intent := workflowIntent(script)
+	intent.Parameters = append(intent.Parameters, parser.Parameter{Name: "token", Type: "secret", Required: true})
+	return intent
+}
+
+// assertNoSecret fails when the secret appears in what
+func assertNoSecret(t *testing.T, what string, data []byte) {
+	t.Helper()
+	if strings.Contains(string(data), testSecret) {
+		t.Errorf("Expected the secret to be masked in %s, got:\n%s", what, data)
+	}
+}
+
+func TestExecute_SecretsRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"auth": r.Header.Get("Authorization"), "query": r.URL.RawQuery})
+	}))
+	defer server.Close()
+
+	intent := secretIntent(`→ log("calling with {token}")
+→ data = http.get("` + server.URL + `/items?key={token}", { Authorization: "Bearer {token}" })
+→ return(auth=data.auth, query=data.query, length=length(token))`)
+	inputs := map[string]string{"text": "x", "token": testSecret}
+
+	var log strings.Builder
+	prev := logWriter
+	logWriter = &log
+	defer func() { logWriter = prev }()
+
+	events := &recorder{}
+	recording := NewCassette()
+	results, err := ExecuteWithOptions(intent, inputs, Options{Observer: events, Cassette: recording})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	// Steps see the value itself; only what leaves the execution is masked
+	want := ExecuteResult{"status": "success", "auth": "Bearer ***", "query": "key=***", "length": int64(len(testSecret))}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("Expected %v, got %v", want, results)
+	}
+	assertNoSecret(t, "the log", []byte(log.String()))
+	if !strings.Contains(log.String(), "calling with ***") {
+		t.Errorf("Expected the masked log message, got %q", log.String())
+	}
+	data, _ := json.Marshal(events.events)
+	assertNoSecret(t, "the events", data)
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := recording.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	assertNoSecret(t, "the cassette", data)
+
+	// The masked recording matches a replay with the same secret
+	server.Close()
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := ExecuteWithOptions(intent, inputs, Options{Cassette: cassette})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	if !reflect.DeepEqual(replayed, want) {
+		t.Errorf("Expected replayed results %v, got %v", want, replayed)
+	}
+}
+
+func TestExecute_SecretsInErrors(t *testing.T) {
+	intent := secretIntent(`→ http.get("http://127.0.0.1:1/{token}")`)
+	events := &recorder{}
+	_, err := ExecuteWithOptions(intent, map[string]string{"text": "x", "token": testSecret}, Options{Observer: events})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	assertNoSecret(t, "the error", []byte(err.Error()))
+	if !strings.Contains(err.Error(), "127.0.0.1:1/***") {
+		t.Errorf("Expected the masked URL in the error, got %v", err)
+	}
+	var stepErr *StepError
+	if !errors.As(err, &stepErr) {
+		t.Errorf("Expected the masked error to wrap *StepError, got %T", err)
+	}
+	data, _ := json.Marshal(events.events)
+	assertNoSecret(t, "the events", data)
+}
+
+func TestExecute_SecretsNotSaved(t *testing.T) {
+	dir := t.TempDir()
+	intent := secretIntent("")
+	intent.Outputs = []parser.Output{{Name: "result", Type: "string"}}
+	results, err := ExecuteWithOptions(intent, map[string]string{"text": testSecret + "!", "token": testSecret}, Options{OutputDir: dir})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if want := "Intent 'workflow' processed inputs: text=***!, token=***"; results["result"] != want {
+		t.Errorf("Expected %q, got %q", want, results["result"])
+	}
+	for _, file := range []string{"results.json", "result.txt"} {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertNoSecret(t, file, data)
+	}
+
+	cacheDir := t.TempDir()
+	cached := secretIntent(`→ return(echo="{token}")`)
+	cached.Cache = "1h"
+	if _, err := ExecuteWithOptions(cached, map[string]string{"text": "x", "token": testSecret}, Options{Cache: NewCache(cacheDir)}); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(cacheDir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("Expected one cache entry, got %v", files)
+	}
+	data, _ := os.ReadFile(files[0])
+	assertNoSecret(t, "the cache entry", data)
+}
+
+func TestPlanIntent_Secrets(t *testing.T) {
+	intent := secretIntent(`→ http.get("https://api.example.com/items?key={token}")`)
+	plan, err := PlanIntent(intent, map[string]string{"text": "x", "token": testSecret}, Options{})
+	if err != nil {
+		t.Fatalf("PlanIntent failed: %v", err)
+	}
+	data, _ := json.Marshal(plan)
+	assertNoSecret(t, "the plan", data)
+	if plan.Inputs["token"] != secretMask || plan.Requests[0].URL != "https://api.example.com/items?key=***" {
+		t.Errorf("Expected masked inputs and requests, got %s", data)
+	}
+}
@@ -0,0 +1,251 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// StepHandler implements a workflow step with side effects, such as
+// `http.get(url)` or a custom `db.lookup(id)`. Steps can only be used as a
+// whole workflow line, never inside an expression.
+type StepHandler interface {
+	// Spec describes the step's name, arguments, result and the
+	// capabilities a package must declare to use it
+	Spec() StepSpec
+	// Run executes the step. Arguments have already been checked
+	// against the spec. Steps that wait on I/O should stop when
+	// call.Context is done.
+	Run(call *StepCall) (interface{}, error)
+}
+
+// StepSpec declares a step's schema. Types are ITML types or "any", with
+// alternatives separated by '|', as in "string|array".
+type StepSpec struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description,omitempty"`
+	Args         []ArgSpec `json:"args,omitempty"`
+	Returns      string    `json:"returns,omitempty"`      // empty means any
+	Capabilities []string  `json:"capabilities,omitempty"` // e.g. "http.outbound"
+}
+
+// ArgSpec declares one positional argument of a step. Optional arguments
+// must come last.
+type ArgSpec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type,omitempty"` // empty means any
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// StepCall is a single invocation of a step
+type StepCall struct {
+	Context context.Context        // canceled when the step must stop
+	Step    string                 // step name
+	Args    []interface{}          // evaluated arguments, in spec order
+	Inputs  map[string]interface{} // typed input values of the intent
+	Network *pack.NetworkPolicy    // nil when no package policy applies
+	Log     io.Writer              // destination for diagnostic messages
+	// Cassette records or replays the step's external calls; steps
+	// making HTTP requests send them with Cassette.Transport, passing
+	// their response size limit
+	Cassette *Cassette
+
+	exec *ExecutionContext // the execution running the step
+	span int64             // the step's span in events
+}
+
+// NewStep returns a StepHandler that runs fn
+func NewStep(spec StepSpec, fn func(call *StepCall) (interface{}, error)) StepHandler {
+	return &funcStep{spec: spec, fn: fn}
+}
+
+type funcStep struct {
+	spec StepSpec
+	fn   func(call *StepCall) (interface{}, error)
+}
+
+func (s *funcStep) Spec() StepSpec                          { return s.spec }
+func (s *funcStep) Run(call *StepCall) (interface{}, error) { return s.fn(call) }
+
+// Registry holds the steps available to workflows
+type Registry struct {
+	mu    sync.RWMutex
+	steps map[string]StepHandler
+}
+
+// DefaultRegistry is used by executions that do not set Options.Steps
+var DefaultRegistry *Registry
+
+// The call step runs workflows, which look steps up in DefaultRegistry, so
+// it is set up here rather than in its declaration
+func init() {
+	DefaultRegistry = NewRegistry()
+}
+
+// Register adds a step to DefaultRegistry
+func Register(h StepHandler) error {
+	return DefaultRegistry.Register(h)
+}
+
+// NewRegistry returns a registry holding the built-in steps
+func NewRegistry() *Registry {
+	r := &Registry{steps: make(map[string]StepHandler)}
+	for _, h := range builtinSteps() {
+		if err := r.Register(h); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// stepNamePattern matches step names: a name, optionally qualified by one
+// namespace as in "db.lookup"
+var stepNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Register adds a step. Names must be unique and cannot shadow an
+// expression function or `return`.
+func (r *Registry) Register(h StepHandler) error {
+	spec := h.Spec()
+	if !stepNamePattern.MatchString(spec.Name) {
+		return fmt.Errorf("invalid step name %q (expected name or namespace.name)", spec.Name)
+	}
+	if _, ok := functions[spec.Name]; ok || spec.Name == "return" {
+		return fmt.Errorf("step name %q is reserved", spec.Name)
+	}
+	for i, arg := range spec.Args {
+		if !arg.Optional && i > 0 && spec.Args[i-1].Optional {
+			return fmt.Errorf("step %s: required argument '%s' follows an optional one", spec.Name, arg.Name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.steps[spec.Name]; ok {
+		return fmt.Errorf("step %q is already registered", spec.Name)
+	}
+	r.steps[spec.Name] = h
+	return nil
+}
+
+// Lookup returns the step registered under name
+func (r *Registry) Lookup(name string) (StepHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.steps[name]
+	return h, ok
+}
+
+// Names returns the registered step names in sorted order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.steps))
+	for name := range r.steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stepFunction describes a step's arguments in the form the checker uses
+// for built-in functions
+func stepFunction(spec StepSpec) function {
+	f := function{maxArgs: len(spec.Args), sig: signature{returns: specKind(spec.Returns)}}
+	for _, arg := range spec.Args {
+		if !arg.Optional {
+			f.minArgs++
+		}
+		f.sig.args = append(f.sig.args, specKind(arg.Type))
+	}
+	if len(f.sig.args) == 0 {
+		f.sig.args = []string{"any"}
+	}
+	return f
+}
+
+// specKind maps the types in a spec, such as "text|url", to kinds
+func specKind(t string) string {
+	if t == "" {
+		return "any"
+	}
+	alts := strings.Split(t, "|")
+	for i, alt := range alts {
+		alts[i] = typeKind(alt)
+	}
+	return strings.Join(alts, "|")
+}
+
+// missingCapabilities returns the capabilities a step requires that are
+// not granted. A nil grant list allows everything.
+func missingCapabilities(spec StepSpec, granted []string) []string {
+	if granted == nil {
+		return nil
+	}
+	var missing []string
+	for _, want := range spec.Capabilities {
+		found := false
+		for _, have := range granted {
+			if have == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+	return missing
+}
+
+func builtinSteps() []StepHandler {
+	return []StepHandler{
+		NewStep(StepSpec{
+			Name:        "log",
+			Description: "Write a message to the log and pass it on",
+			Args:        []ArgSpec{{Name: "message"}},
+			Returns:     "string",
+		}, stepLog),
+		NewStep(StepSpec{
+			Name:        "http.get",
+			Description: "Fetch a URL; JSON responses are decoded",
+			Args: []ArgSpec{
+				{Name: "url", Type: "string"},
+				{Name: "headers", Type: "object", Optional: true},
+			},
+			Capabilities: []string{"http.outbound"},
+		}, httpStep(http.MethodGet)),
+		NewStep(StepSpec{
+			Name:        "http.post",
+			Description: "Send a body to a URL; strings are sent as text, other values as JSON",
+			Args: []ArgSpec{
+				{Name: "url", Type: "string"},
+				{Name: "body"},
+				{Name: "headers", Type: "object", Optional: true},
+			},
+			Capabilities: []string{"http.outbound"},
+		}, httpStep(http.MethodPost)),
+		NewStep(StepSpec{
+			Name:        "call",
+			Description: "Run another intent, a .itml file or an installed package, and return its results",
+			Args: []ArgSpec{
+				{Name: "intent", Type: "string"},
+				{Name: "inputs", Type: "object", Optional: true},
+			},
+			Returns: "object",
+		}, callStep),
+	}
+}
+
+// stepLog writes its message and passes it on as the step result
+func stepLog(call *StepCall) (interface{}, error) {
+	text := formatValue(call.Args[0])
+	fmt.Fprintln(call.Log, text)
+	return text, nil
+}
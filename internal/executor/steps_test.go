@@ -0,0 +1,205 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func lookupStep() StepHandler {
+	return NewStep(StepSpec{
+		Name:         "db.lookup",
+		Args:         []ArgSpec{{Name: "id", Type: "string"}, {Name: "fields", Type: "array", Optional: true}},
+		Returns:      "object",
+		Capabilities: []string{"db.read"},
+	}, func(call *StepCall) (interface{}, error) {
+		if call.Args[0] == "bad" {
+			return "not an object", nil
+		}
+		return map[string]interface{}{"id": call.Args[0], "user": call.Inputs["text"]}, nil
+	})
+}
+
+func TestRegistry_CustomStep(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(lookupStep()); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	script := `→ row = db.lookup("42")
+→ return(id=row.id, user=row.user)`
+	result, err := ExecuteWithOptions(workflowIntent(script), map[string]string{"text": "ann"}, Options{Steps: registry})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["id"] != "42" || result["user"] != "ann" {
+		t.Errorf("Unexpected results: %v", result)
+	}
+
+	// The default registry does not know the step
+	if _, err := Execute(workflowIntent(script), map[string]string{"text": "ann"}, ""); err == nil || !strings.Contains(err.Error(), "unknown function 'db.lookup'") {
+		t.Errorf("Expected unknown function error, got %v", err)
+	}
+}
+
+func TestRegistry_StepErrors(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(lookupStep()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		script       string
+		capabilities []string
+		want         string
+	}{
+		{"arity", `→ db.lookup()`, nil, "db.lookup expects 1 to 2 arguments, got 0"},
+		{"argument kind", `→ db.lookup(limit)`, nil, "argument 1 of db.lookup must be string, got integer"},
+		{"runtime argument kind", `→ db.lookup(input.text | default(null))`, nil, "argument 'id' must be string, got null"},
+		{"result kind", `→ db.lookup("bad")`, nil, "db.lookup returned string, but declares object"},
+		{"capability", `→ db.lookup("42")`, []string{"http.outbound"}, "step db.lookup requires db.read, which the package does not declare"},
+		{"nested", `→ length(db.lookup("42"))`, nil, "db.lookup can only be used as a workflow step"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": ""}, Options{
+				Steps:        registry,
+				Capabilities: tt.capabilities,
+			})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_CapabilityViolationAtRunTime(t *testing.T) {
+	registry := NewRegistry()
+	if err := registry.Register(lookupStep()); err != nil {
+		t.Fatal(err)
+	}
+	// CheckWorkflow catches this first; the step itself reports a policy violation
+	expr, err := parser.ParseExpr("", `db.lookup("42")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &ExecutionContext{Intent: workflowIntent(""), Steps: registry, Capabilities: []string{}}
+	run := &workflowRun{ctx: ctx, scope: map[string]interface{}{}}
+	h, _ := registry.Lookup("db.lookup")
+	_, err = run.runHandler(expr.(*parser.CallExpr), h, 0)
+	var violation *pack.PolicyViolation
+	if !errors.As(err, &violation) || violation.Policy != "capabilities" {
+		t.Errorf("Expected a capabilities policy violation, got %v", err)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	registry := NewRegistry()
+	tests := []struct {
+		spec StepSpec
+		want string
+	}{
+		{StepSpec{Name: "db..lookup"}, "invalid step name"},
+		{StepSpec{Name: "upper"}, `step name "upper" is reserved`},
+		{StepSpec{Name: "return"}, `step name "return" is reserved`},
+		{StepSpec{Name: "http.get"}, `step "http.get" is already registered`},
+		{StepSpec{Name: "db.find", Args: []ArgSpec{{Name: "a", Optional: true}, {Name: "b"}}}, "required argument 'b' follows an optional one"},
+	}
+	for _, tt := range tests {
+		err := registry.Register(NewStep(tt.spec, nil))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Register(%s): expected error containing %q, got %v", tt.spec.Name, tt.want, err)
+		}
+	}
+
+	want := []string{"call", "http.get", "http.post", "log"}
+	if got := registry.Names(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected built-in steps %v, got %v", want, got)
+	}
+}
+
+// TestPluginProcess is not a real test: it acts as a plugin binary when
+// the test executable is started by TestRegistry_LoadPlugin
+func TestPluginProcess(t *testing.T) {
+	if os.Getenv("INTENT_TEST_PLUGIN") != "1" {
+		return
+	}
+	var req pluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	var resp pluginResponse
+	switch {
+	case req.Method == "describe":
+		resp.Steps = []StepSpec{{
+			Name:    "kv.get",
+			Args:    []ArgSpec{{Name: "key", Type: "string"}},
+			Returns: "string",
+		}}
+	case req.Args[0] == "missing":
+		resp.Error = "no such key"
+	case req.Args[0] == "inputs":
+		names := make([]string, 0, len(req.Inputs))
+		for name := range req.Inputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		resp.Result = strings.Join(names, ",")
+	default:
+		fmt.Fprintln(os.Stderr, "looking up", req.Args[0])
+		resp.Result = fmt.Sprintf("value of %s for %s", req.Args[0], req.Inputs["text"])
+	}
+	json.NewEncoder(os.Stdout).Encode(resp)
+	os.Exit(0)
+}
+
+func TestRegistry_LoadPlugin(t *testing.T) {
+	t.Setenv("INTENT_TEST_PLUGIN", "1")
+	registry := NewRegistry()
+	names, err := registry.LoadPlugin(os.Args[0], "-test.run=^TestPluginProcess$")
+	if err != nil {
+		t.Fatalf("LoadPlugin failed: %v", err)
+	}
+	if len(names) != 1 || names[0] != "kv.get" {
+		t.Fatalf("Expected kv.get, got %v", names)
+	}
+
+	var log strings.Builder
+	prev := logWriter
+	logWriter = &log
+	defer func() { logWriter = prev }()
+
+	result, err := ExecuteWithOptions(workflowIntent(`→ kv.get("colour")`), map[string]string{"text": "ann"}, Options{Steps: registry})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["result"] != "value of colour for ann" {
+		t.Errorf("Unexpected results: %v", result)
+	}
+	if !strings.Contains(log.String(), "looking up colour") {
+		t.Errorf("Expected plugin stderr in the log, got %q", log.String())
+	}
+
+	_, err = ExecuteWithOptions(workflowIntent(`→ kv.get("missing")`), map[string]string{"text": "ann"}, Options{Steps: registry})
+	if err == nil || !strings.Contains(err.Error(), "kv.get: no such key") {
+		t.Errorf("Expected plugin error, got %v", err)
+	}
+
+	// Secret inputs reach a plugin only as arguments
+	result, err = ExecuteWithOptions(secretIntent(`→ kv.get("inputs")`), map[string]string{"text": "ann", "token": testSecret}, Options{Steps: registry})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["result"] != "limit,text" {
+		t.Errorf("Expected the plugin not to receive the secret input, got inputs %v", result["result"])
+	}
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+)
+
+// scriptEnv lists the environment variables passed on to subprocess
+// scripts. Everything else, including credentials, is dropped.
+var scriptEnv = []string{"PATH", "LANG", "LC_ALL", "SYSTEMROOT"}
+
+// pythonInterpreter returns the interpreter for python: scripts
+func pythonInterpreter() string {
+	if _, err := exec.LookPath("python3"); err == nil {
+		return "python3"
+	}
+	return "python"
+}
+
+// splitInterpreter splits the body of an exec: script, such as
+// "ruby\nputs 1", into the interpreter and the script it runs
+func splitInterpreter(s string) (string, string) {
+	s = strings.TrimLeft(s, " \t")
+	if i := strings.IndexAny(s, " \t\r\n"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// executeSubprocess runs a script with an external interpreter. The
+// script runs in a fresh temporary directory with a scrubbed environment,
+// reads the typed inputs as a JSON object on stdin and writes its result
+// as JSON on stdout, which maps onto the outputs as for javascript:
+// scripts. Anything it writes to stderr is returned under "stderr", with
+// secret values masked, for debugging, and included in the error when the
+// script fails. The package's script policy bounds its run time and
+// output, and the package must declare the process.exec capability. As
+// with workflow steps, an intent outside a package is not restricted.
+func executeSubprocess(interpreter, code string, ctx *ExecutionContext) (ExecuteResult, error) {
+	if interpreter == "" {
+		return nil, fmt.Errorf("exec: script names no interpreter (expected exec:<interpreter> followed by the script)")
+	}
+	if ctx.Capabilities != nil && !hasCapability(ctx.Capabilities, "process.exec") {
+		return nil, &pack.PolicyViolation{
+			Policy: "capabilities",
+			Reason: fmt.Sprintf("running %s scripts requires process.exec, which the package does not declare", interpreter),
+		}
+	}
+	path, err := exec.LookPath(interpreter)
+	if err != nil {
+		return nil, fmt.Errorf("interpreter %s not found: %w", interpreter, err)
+	}
+
+	dir, err := os.MkdirTemp("", "intent-script-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	scriptPath := filepath.Join(dir, "script")
+	if err := os.WriteFile(scriptPath, []byte(code), 0600); err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(ctx.Values)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode inputs: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx.Context, ctx.Scripts.Timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{limit: ctx.Scripts.MaxOutput, overflow: cancel}
+	stderr := &cappedBuffer{limit: ctx.Scripts.MaxOutput, overflow: cancel}
+	cmd := exec.CommandContext(runCtx, path, scriptPath)
+	cmd.Dir = dir
+	cmd.Env = scrubbedEnv(dir)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+
+	switch {
+	case stdout.full || stderr.full:
+		return nil, &pack.PolicyViolation{
+			Policy: "scripts.maxOutput",
+			Reason: fmt.Sprintf("script wrote more than %d bytes", ctx.Scripts.MaxOutput),
+		}
+	case ctx.Context.Err() != nil:
+		return nil, ctx.Context.Err()
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return nil, &pack.PolicyViolation{
+			Policy: "scripts.timeout",
+			Reason: fmt.Sprintf("script ran longer than %s", ctx.Scripts.Timeout),
+		}
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", interpreter, err)
+	}
+
+	var value interface{}
+	if out := bytes.TrimSpace(stdout.Bytes()); len(out) > 0 {
+		if err := json.Unmarshal(out, &value); err != nil {
+			return nil, fmt.Errorf("%s: script output is not JSON: %w", interpreter, err)
+		}
+	}
+	results := scriptResults(value, ctx)
+	if len(stderr.Bytes()) > 0 {
+		results["stderr"] = ctx.secrets.redact(stderr.String())
+	}
+	return results, nil
+}
+
+// scrubbedEnv returns the environment of a subprocess script, with its
+// home and temporary directory set to dir
+func scrubbedEnv(dir string) []string {
+	env := []string{"HOME=" + dir, "TMPDIR=" + dir, "TMP=" + dir, "TEMP=" + dir}
+	for _, key := range scriptEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// cappedBuffer keeps up to limit bytes and calls overflow once when more
+// are written, discarding the rest. The buffer is not embedded so that
+// io.Copy cannot bypass Write through bytes.Buffer.ReadFrom.
+type cappedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	full     bool
+	overflow func()
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - int64(b.buf.Len()); int64(len(p)) > room {
+		if !b.full {
+			b.full = true
+			b.overflow()
+		}
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) Bytes() []byte  { return b.buf.Bytes() }
+func (b *cappedBuffer) String() string { return b.buf.String() }
@@ -0,0 +1,124 @@
+package executor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func requireInterpreter(t *testing.T, name string) {
+	t.Helper()
+	if _, err := exec.LookPath(name); err != nil {
+		t.Skipf("%s not available", name)
+	}
+}
+
+func TestExecute_Python(t *testing.T) {
+	requireInterpreter(t, pythonInterpreter())
+
+	script := `python:
+import json, os, sys
+data = json.load(sys.stdin)
+print("debug", file=sys.stderr)
+json.dump({
+    "words": data["text"].split(),
+    "limit": data["limit"] * 2,
+    "cwd_is_home": os.getcwd() == os.environ["HOME"],
+    "secret": os.environ.get("INTENT_TEST_SECRET"),
+}, sys.stdout)
+`
+	t.Setenv("INTENT_TEST_SECRET", "hunter2")
+	result, err := ExecuteWithOptions(workflowIntent(script), map[string]string{"text": "a b"}, Options{Capabilities: []string{"process.exec"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := ExecuteResult{
+		"status":      "success",
+		"words":       []interface{}{"a", "b"},
+		"limit":       float64(4),
+		"cwd_is_home": true,
+		"secret":      nil,
+		"stderr":      "debug\n",
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+}
+
+func TestExecute_SubprocessStderrRedacted(t *testing.T) {
+	requireInterpreter(t, "sh")
+
+	intent := secretIntent("exec:sh\necho \"inputs: $(cat)\" >&2")
+	result, err := ExecuteWithOptions(intent, map[string]string{"text": "x", "token": testSecret}, Options{Capabilities: []string{"process.exec"}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	stderr, _ := result["stderr"].(string)
+	if !strings.Contains(stderr, `"token":"***"`) {
+		t.Errorf("Expected the secret to be masked in stderr, got %q", stderr)
+	}
+	assertNoSecret(t, "stderr", []byte(stderr))
+}
+
+func TestExecute_ExecInterpreter(t *testing.T) {
+	requireInterpreter(t, "sh")
+
+	intent := workflowIntent("exec:sh\nread line\necho \"\\\"$(pwd)\\\"\"")
+	intent.Outputs = []parser.Output{{Name: "dir", Type: "string"}}
+	// Outside a package no process.exec grant is needed
+	result, err := ExecuteWithOptions(intent, map[string]string{"text": "x"}, Options{})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	dir, _ := result["dir"].(string)
+	if !strings.Contains(dir, "intent-script-") {
+		t.Errorf("Expected the script to run in a temporary directory, got %v", result)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", dir, err)
+	}
+}
+
+func TestExecute_SubprocessErrors(t *testing.T) {
+	requireInterpreter(t, "sh")
+
+	limits := &pack.ScriptPolicy{Timeout: 100 * time.Millisecond, MaxOutput: 1024}
+	granted := []string{"process.exec"}
+	tests := []struct {
+		name   string
+		script string
+		opts   Options
+		want   string
+	}{
+		{"exit status", "exec:sh\necho broken >&2; exit 3", Options{Capabilities: granted}, "sh: exit status 3: broken"},
+		{"not JSON", "exec:sh\necho hello", Options{Capabilities: granted}, "sh: script output is not JSON"},
+		{"timeout", "exec:sh\nsleep 5", Options{Scripts: limits, Capabilities: granted}, "policy violation (scripts.timeout): script ran longer than 100ms"},
+		{"output cap", "exec:sh\nwhile true; do echo xxxxxxxxxxxxxxxx; done", Options{Scripts: limits, Capabilities: granted}, "policy violation (scripts.maxOutput)"},
+		{"capability", "exec:sh\necho 1", Options{Capabilities: []string{"http.outbound"}}, "requires process.exec, which the package does not declare"},
+		{"missing interpreter", "exec:no-such-interpreter\n1", Options{Capabilities: granted}, "interpreter no-such-interpreter not found"},
+		{"no interpreter", "exec:", Options{}, "names no interpreter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			_, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": "x"}, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+			var violation *pack.PolicyViolation
+			if strings.Contains(tt.want, "policy violation") && !errors.As(err, &violation) {
+				t.Errorf("Expected a *pack.PolicyViolation, got %T", err)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("Expected the script to be stopped, took %v", elapsed)
+			}
+		})
+	}
+}
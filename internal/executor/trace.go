@@ -0,0 +1,273 @@
+package executor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Types of Event
+const (
+	EventExecutionStart = "execution.start"
+	EventExecutionEnd   = "execution.end"
+	EventStepStart      = "step.start"
+	EventStepEnd        = "step.end"
+)
+
+// Event describes one thing that happened during an execution. The
+// execution is span 1; every step run gets a new span whose parent is the
+// execution or the block containing it, so nested and repeated steps can
+// be told apart. An intent run by a call step reports its own execution
+// events, as a span whose parent is the call step.
+type Event struct {
+	Type     string                 `json:"type"`
+	Time     time.Time              `json:"time"`
+	Intent   string                 `json:"intent"`
+	Span     int64                  `json:"span"`
+	Parent   int64                  `json:"parent,omitempty"`
+	Step     string                 `json:"step,omitempty"` // step id, as in StepError
+	At       string                 `json:"at,omitempty"`
+	Expr     string                 `json:"expr,omitempty"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"`   // typed inputs, or a step's arguments
+	Output   interface{}            `json:"output,omitempty"`   // results, or a step's value
+	Duration time.Duration          `json:"duration,omitempty"` // in nanoseconds
+	Attempts int                    `json:"attempts,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	Handled  string                 `json:"handled,omitempty"` // how a step failure was recovered from
+	Cached   bool                   `json:"cached,omitempty"`  // the results were reused from the cache
+}
+
+// Observer receives the events of an execution as they happen. Observe
+// must not modify the event's values and may be called from several
+// goroutines.
+type Observer interface {
+	Observe(event Event)
+}
+
+// executionSpan is the span of the execution itself
+const executionSpan = 1
+
+// observe sends e to the observer, if any, filling in its time and intent
+func (ctx *ExecutionContext) observe(e Event) {
+	if ctx.Observer == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Intent = ctx.Intent.Name
+	if len(ctx.secrets) > 0 {
+		if e.Inputs != nil {
+			e.Inputs = ctx.secrets.redactValue(e.Inputs).(map[string]interface{})
+		}
+		e.Output = ctx.secrets.redactValue(e.Output)
+		e.Error = ctx.secrets.redact(e.Error)
+	}
+	ctx.Observer.Observe(e)
+}
+
+// newSpan returns an id for the next step span. Called intents take theirs
+// from the outermost execution, so that ids are unique across the trace.
+func (ctx *ExecutionContext) newSpan() int64 {
+	if ctx.caller != nil {
+		return ctx.caller.newSpan()
+	}
+	return ctx.spans.Add(1) + executionSpan
+}
+
+// Formats written by TraceWriter
+const (
+	TraceJSONL = "jsonl"     // one JSON event per line, written as it happens
+	TraceOTLP  = "otlp-json" // an OpenTelemetry (OTLP/JSON) trace, written on Close
+)
+
+// TraceWriter is an Observer that persists events in one of the trace
+// formats. Close must be called once the execution has returned.
+type TraceWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	format  string
+	enc     *json.Encoder
+	traceID string
+	spans   []*otlpSpan
+	open    map[int64]*otlpSpan
+	err     error
+}
+
+// NewTraceWriter returns a TraceWriter writing to w in the given format
+func NewTraceWriter(w io.Writer, format string) (*TraceWriter, error) {
+	t := &TraceWriter{w: w, format: format}
+	switch format {
+	case TraceJSONL:
+		t.enc = json.NewEncoder(w)
+	case TraceOTLP:
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		t.traceID = hex.EncodeToString(id)
+		t.open = make(map[int64]*otlpSpan)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q (expected %s or %s)", format, TraceJSONL, TraceOTLP)
+	}
+	return t, nil
+}
+
+// Observe records an event. The first write error is returned by Close.
+func (t *TraceWriter) Observe(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	if t.format == TraceJSONL {
+		t.err = t.enc.Encode(e)
+		return
+	}
+
+	switch e.Type {
+	case EventExecutionStart, EventStepStart:
+		span := &otlpSpan{
+			TraceID:   t.traceID,
+			SpanID:    spanID(e.Span),
+			Name:      e.Intent,
+			Kind:      otlpSpanKindInternal,
+			StartTime: strconv.FormatInt(e.Time.UnixNano(), 10),
+		}
+		if e.Parent != 0 {
+			span.ParentSpanID = spanID(e.Parent)
+		}
+		if e.Type == EventStepStart {
+			span.Name = e.Expr
+		}
+		t.open[e.Span] = span
+		t.spans = append(t.spans, span)
+		span.attr("intent.name", e.Intent)
+		span.attr("step.id", e.Step)
+		span.attr("step.at", e.At)
+		span.attr("inputs", e.Inputs)
+	case EventExecutionEnd, EventStepEnd:
+		span, ok := t.open[e.Span]
+		if !ok {
+			return
+		}
+		delete(t.open, e.Span)
+		span.EndTime = strconv.FormatInt(e.Time.UnixNano(), 10)
+		span.attr("inputs", e.Inputs)
+		span.attr("output", e.Output)
+		if e.Attempts > 1 {
+			span.attr("step.attempts", e.Attempts)
+		}
+		span.attr("error.handled", e.Handled)
+		span.Status.Code = otlpStatusOK
+		if e.Error != "" && e.Handled == "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: e.Error}
+		} else if e.Error != "" {
+			span.attr("error.message", e.Error)
+		}
+	}
+}
+
+// Close writes the OTLP trace and reports the first error writing events
+func (t *TraceWriter) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil || t.format != TraceOTLP {
+		return t.err
+	}
+	doc := map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttr{{Key: "service.name", Value: otlpValue{StringValue: "intent-cli"}}},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/intentregistry/intent-cli/internal/executor"},
+				"spans": t.spans,
+			}},
+		}},
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
+
+// spanID formats a span number as an 8-byte OTLP span id
+func spanID(span int64) string {
+	return fmt.Sprintf("%016x", span)
+}
+
+// The parts of the OTLP/JSON encoding used by TraceWriter
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusOK         = 1
+	otlpStatusError      = 2
+)
+
+type otlpSpan struct {
+	TraceID      string     `json:"traceId"`
+	SpanID       string     `json:"spanId"`
+	ParentSpanID string     `json:"parentSpanId,omitempty"`
+	Name         string     `json:"name"`
+	Kind         int        `json:"kind"`
+	StartTime    string     `json:"startTimeUnixNano"`
+	EndTime      string     `json:"endTimeUnixNano,omitempty"`
+	Attributes   []otlpAttr `json:"attributes,omitempty"`
+	Status       otlpStatus `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+// attr adds an attribute, skipping empty values. Values other than
+// strings and integers are stored as JSON.
+func (s *otlpSpan) attr(key string, value interface{}) {
+	var v otlpValue
+	switch val := value.(type) {
+	case nil:
+		return
+	case string:
+		if val == "" {
+			return
+		}
+		v.StringValue = val
+	case int:
+		v.IntValue = strconv.Itoa(val)
+	case map[string]interface{}:
+		if len(val) == 0 {
+			return
+		}
+		v.StringValue = formatTraceValue(val)
+	default:
+		v.StringValue = formatTraceValue(val)
+	}
+	s.Attributes = append(s.Attributes, otlpAttr{Key: key, Value: v})
+}
+
+// formatTraceValue renders a value as JSON, or with %v if it has no JSON form
+func formatTraceValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder is an Observer keeping every event
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) Observe(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestExecute_Events(t *testing.T) {
+	registry, _ := failingSteps()
+	registry.Register(builtinSteps()[0])
+	script := `→ flaky(2) retry=1 backoff="1ms"
+→ for w in split(text, " "):
+    → log(w)
+→ flaky(9) on_error=continue
+→ return(done=true)`
+	events := &recorder{}
+	_, err := ExecuteWithOptions(workflowIntent(script), map[string]string{"text": "a b"}, Options{Steps: registry, Observer: events})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	type summary struct {
+		Type     string
+		Span     int64
+		Parent   int64
+		Step     string
+		Inputs   map[string]interface{}
+		Output   interface{}
+		Attempts int
+		Error    string
+		Handled  string
+	}
+	var got []summary
+	for _, e := range events.events {
+		if e.Intent != "workflow" || e.Time.IsZero() {
+			t.Errorf("Expected intent and time to be set, got %+v", e)
+		}
+		if strings.HasPrefix(e.Type, "execution") {
+			e.Inputs, e.Output = nil, nil
+		}
+		got = append(got, summary{e.Type, e.Span, e.Parent, e.Step, e.Inputs, e.Output, e.Attempts, e.Error, e.Handled})
+	}
+	want := []summary{
+		{Type: EventExecutionStart, Span: 1},
+		{Type: EventStepStart, Span: 2, Parent: 1, Step: "step1"},
+		{Type: EventStepEnd, Span: 2, Parent: 1, Step: "step1", Inputs: map[string]interface{}{"n": int64(2)}, Output: "ok", Attempts: 2},
+		{Type: EventStepStart, Span: 3, Parent: 1, Step: "step2"},
+		{Type: EventStepStart, Span: 4, Parent: 3, Step: "workflow:3:5"},
+		{Type: EventStepEnd, Span: 4, Parent: 3, Step: "workflow:3:5", Inputs: map[string]interface{}{"message": "a"}, Output: "a", Attempts: 1},
+		{Type: EventStepStart, Span: 5, Parent: 3, Step: "workflow:3:5"},
+		{Type: EventStepEnd, Span: 5, Parent: 3, Step: "workflow:3:5", Inputs: map[string]interface{}{"message": "b"}, Output: "b", Attempts: 1},
+		{Type: EventStepEnd, Span: 3, Parent: 1, Step: "step2", Output: []interface{}{"a", "b"}},
+		{Type: EventStepStart, Span: 6, Parent: 1, Step: "step3"},
+		{Type: EventStepEnd, Span: 6, Parent: 1, Step: "step3", Inputs: map[string]interface{}{"n": int64(9)}, Attempts: 1, Error: "workflow:4:1: service unavailable", Handled: "continue"},
+		{Type: EventStepStart, Span: 7, Parent: 1, Step: "step4"},
+		{Type: EventStepEnd, Span: 7, Parent: 1, Step: "step4", Attempts: 1},
+		{Type: EventExecutionEnd, Span: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected events:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestExecute_EventsOnFailure(t *testing.T) {
+	events := &recorder{}
+	_, err := ExecuteWithOptions(workflowIntent(`→ 1 / 0`), map[string]string{"text": "x"}, Options{Observer: events})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if n := len(events.events); n != 4 {
+		t.Fatalf("Expected 4 events, got %d: %+v", n, events.events)
+	}
+	for _, e := range events.events[2:] {
+		if e.Error != err.Error() {
+			t.Errorf("Expected %s to report %q, got %q", e.Type, err, e.Error)
+		}
+	}
+}
+
+func TestTraceWriter(t *testing.T) {
+	run := func(format string) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		trace, err := NewTraceWriter(&buf, format)
+		if err != nil {
+			t.Fatalf("NewTraceWriter failed: %v", err)
+		}
+		script := "→ if text == \"x\":\n    → upper(text)\n→ 1 / 0 on_error=fallback(0)"
+		if _, err := ExecuteWithOptions(workflowIntent(script), map[string]string{"text": "x"}, Options{Observer: trace}); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if err := trace.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	t.Run("jsonl", func(t *testing.T) {
+		var types []string
+		scanner := bufio.NewScanner(bytes.NewReader(run(TraceJSONL)))
+		for scanner.Scan() {
+			var e Event
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				t.Fatalf("Invalid event %s: %v", scanner.Text(), err)
+			}
+			types = append(types, e.Type)
+		}
+		if len(types) != 8 || types[0] != EventExecutionStart || types[7] != EventExecutionEnd {
+			t.Errorf("Unexpected events: %v", types)
+		}
+	})
+
+	t.Run("otlp-json", func(t *testing.T) {
+		var doc struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal(run(TraceOTLP), &doc); err != nil {
+			t.Fatalf("Invalid trace: %v", err)
+		}
+		spans := doc.ResourceSpans[0].ScopeSpans[0].Spans
+		if len(spans) != 4 {
+			t.Fatalf("Expected 4 spans, got %d", len(spans))
+		}
+		want := []struct{ name, parent string }{
+			{"workflow", ""},
+			{`if text == "x":`, spanID(1)},
+			{"upper(text)", spanID(2)},
+			{"1 / 0 on_error=fallback(0)", spanID(1)},
+		}
+		for i, w := range want {
+			s := spans[i]
+			if s.Name != w.name || s.ParentSpanID != w.parent || s.TraceID != spans[0].TraceID || s.EndTime == "" {
+				t.Errorf("Span %d: expected %q with parent %q, got %+v", i, w.name, w.parent, s)
+			}
+			if s.Status.Code != otlpStatusOK {
+				t.Errorf("Span %d: expected status OK, got %+v", i, s.Status)
+			}
+		}
+	})
+
+	if _, err := NewTraceWriter(&bytes.Buffer{}, "xml"); err == nil || !strings.Contains(err.Error(), "unknown trace format") {
+		t.Errorf("Expected unknown format error, got %v", err)
+	}
+}
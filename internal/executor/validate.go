@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// FieldError describes why a single input failed validation
+type FieldError struct {
+	Param   string `json:"param"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every input that failed validation
+type ValidationError struct {
+	Failures []FieldError `json:"failures"`
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Failures) == 1 {
+		f := e.Failures[0]
+		return fmt.Sprintf("invalid value for parameter '%s': %s", f.Param, f.Message)
+	}
+	lines := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		lines[i] = fmt.Sprintf("  - %s: %s", f.Param, f.Message)
+	}
+	return fmt.Sprintf("%d invalid inputs:\n%s", len(e.Failures), strings.Join(lines, "\n"))
+}
+
+func (e *ValidationError) add(param, format string, args ...interface{}) {
+	e.Failures = append(e.Failures, FieldError{Param: param, Message: fmt.Sprintf(format, args...)})
+}
+
+// ValidateInputs validates input parameters against intent definition.
+// It checks every parameter and returns a *ValidationError listing all
+// failures, in declaration order followed by unknown inputs.
+func ValidateInputs(intent *parser.Intent, inputParams map[string]string) error {
+	verr := &ValidationError{}
+	declared := make(map[string]bool, len(intent.Parameters))
+
+	for i := range intent.Parameters {
+		param := &intent.Parameters[i]
+		declared[param.Name] = true
+
+		value, exists := inputParams[param.Name]
+		if !exists {
+			if param.Required && param.Default == nil {
+				verr.add(param.Name, "required parameter not provided")
+			}
+			continue
+		}
+
+		if err := validateParameterValue(value, param); err != nil {
+			verr.add(param.Name, "%v", err)
+		}
+	}
+
+	var unknown []string
+	for name := range inputParams {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		verr.add(name, "unknown parameter")
+	}
+
+	if len(verr.Failures) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateParameterValue validates a parameter value against its definition
+func validateParameterValue(value string, param *parser.Parameter) error {
+	// Type check using the same conversion the executor applies
+	typed, err := parser.ConvertValue(value, param.Type)
+	if err != nil {
+		return err
+	}
+
+	rules := &param.Validation
+
+	// Bounds apply to the number itself, or to the length of strings and arrays
+	if rules.Min != nil || rules.Max != nil {
+		var measure float64
+		var what string
+		switch v := typed.(type) {
+		case int64:
+			measure, what = float64(v), "value"
+		case float64:
+			measure, what = v, "value"
+		case string:
+			measure, what = float64(utf8.RuneCountInString(v)), "length"
+		case []interface{}:
+			measure, what = float64(len(v)), "length"
+		}
+		if what != "" {
+			if rules.Min != nil && measure < *rules.Min {
+				return fmt.Errorf("%s %g is less than minimum %g", what, measure, *rules.Min)
+			}
+			if rules.Max != nil && measure > *rules.Max {
+				return fmt.Errorf("%s %g is greater than maximum %g", what, measure, *rules.Max)
+			}
+		}
+	}
+
+	// Check pattern if specified
+	re, err := rules.PatternRegexp()
+	if err != nil {
+		return err
+	}
+	if re != nil && !re.MatchString(value) {
+		if param.Type == "secret" {
+			return fmt.Errorf("value does not match pattern %s", rules.Pattern)
+		}
+		return fmt.Errorf("value %q does not match pattern %s", value, rules.Pattern)
+	}
+
+	// Check options if specified
+	if len(rules.Options) > 0 {
+		found := false
+		for _, option := range rules.Options {
+			if value == option {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("value must be one of: %s", strings.Join(rules.Options, ", "))
+		}
+	}
+
+	return nil
+}
+
+// OutputError lists every declared output the result does not satisfy
+type OutputError struct {
+	Failures []FieldError `json:"failures"`
+}
+
+func (e *OutputError) Error() string {
+	if len(e.Failures) == 1 {
+		f := e.Failures[0]
+		return fmt.Sprintf("invalid output '%s': %s", f.Param, f.Message)
+	}
+	lines := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		lines[i] = fmt.Sprintf("  - %s: %s", f.Param, f.Message)
+	}
+	return fmt.Sprintf("%d invalid outputs:\n%s", len(e.Failures), strings.Join(lines, "\n"))
+}
+
+// ValidateOutputs checks the result of an execution against the intent's
+// declared outputs. Every output must be present with a value of its
+// type and, for the json, markdown and uri formats, its format. Keys that are
+// not declared, such as status, are left alone. It returns an
+// *OutputError listing all failures in declaration order.
+func ValidateOutputs(intent *parser.Intent, results ExecuteResult) error {
+	oerr := &OutputError{}
+	for _, output := range intent.Outputs {
+		value, ok := results[output.Name]
+		if !ok {
+			oerr.Failures = append(oerr.Failures, FieldError{Param: output.Name, Message: "not produced"})
+			continue
+		}
+		if err := validateOutputValue(value, output); err != nil {
+			oerr.Failures = append(oerr.Failures, FieldError{Param: output.Name, Message: err.Error()})
+		}
+	}
+	if len(oerr.Failures) > 0 {
+		return oerr
+	}
+	return nil
+}
+
+// validateOutputValue checks a single output value against its declaration
+func validateOutputValue(value interface{}, output parser.Output) error {
+	got := typeName(value)
+	switch want := typeKind(output.Type); {
+	case want == "any":
+	case got == "null" || !accepts(want, got):
+		return fmt.Errorf("expected %s, got %s", output.Type, got)
+	case want == "integer":
+		if f, ok := value.(float64); ok && f != math.Trunc(f) {
+			return fmt.Errorf("expected %s, got %v", output.Type, value)
+		}
+	}
+	if output.Type == "url" {
+		if _, err := parser.ConvertValue(value.(string), "url"); err != nil {
+			return err
+		}
+	}
+
+	// Other formats are hints for consumers and are not checked
+	switch strings.ToLower(output.Format) {
+	case "json":
+		// Structured values are serialized as JSON; strings must hold it
+		if s, ok := value.(string); ok && !json.Valid([]byte(s)) {
+			return fmt.Errorf("expected a JSON document, got %q", s)
+		}
+	case "markdown":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("markdown format expects a string, got %s", got)
+		}
+	case "uri":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("uri format expects a string, got %s", got)
+		}
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" {
+			return fmt.Errorf("expected a URI with a scheme, got %q", s)
+		}
+	}
+	return nil
+}
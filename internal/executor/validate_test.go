@@ -0,0 +1,165 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func floatPtr(f float64) *float64 { return &f }
+
+func validationIntent() *parser.Intent {
+	return &parser.Intent{
+		Name: "validation",
+		Parameters: []parser.Parameter{
+			{Name: "city", Type: "string", Required: true, Validation: parser.Validation{Pattern: `^[A-Z][a-z]+$`}},
+			{Name: "days", Type: "integer", Validation: parser.Validation{Min: floatPtr(1), Max: floatPtr(14)}},
+			{Name: "code", Type: "string", Validation: parser.Validation{Min: floatPtr(2), Max: floatPtr(3)}},
+			{Name: "units", Type: "string", Validation: parser.Validation{Options: []string{"metric", "imperial"}}},
+			{Name: "verbose", Type: "boolean"},
+		},
+	}
+}
+
+func TestValidateInputs_Valid(t *testing.T) {
+	inputs := map[string]string{"city": "London", "days": "3", "code": "GB", "units": "metric", "verbose": "true"}
+	if err := ValidateInputs(validationIntent(), inputs); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestValidateInputs_ReportsEveryFailure(t *testing.T) {
+	inputs := map[string]string{
+		"city":    "london",
+		"days":    "30",
+		"code":    "GBRX",
+		"units":   "kelvin",
+		"verbose": "maybe",
+		"extra":   "1",
+	}
+
+	err := ValidateInputs(validationIntent(), inputs)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+
+	want := []struct{ param, msg string }{
+		{"city", "does not match pattern"},
+		{"days", "greater than maximum 14"},
+		{"code", "length 4 is greater than maximum 3"},
+		{"units", "must be one of: metric, imperial"},
+		{"verbose", "invalid boolean"},
+		{"extra", "unknown parameter"},
+	}
+	if len(verr.Failures) != len(want) {
+		t.Fatalf("Expected %d failures, got %d: %v", len(want), len(verr.Failures), err)
+	}
+	for i, w := range want {
+		f := verr.Failures[i]
+		if f.Param != w.param || !strings.Contains(f.Message, w.msg) {
+			t.Errorf("Failure %d: expected %s containing %q, got %s: %q", i, w.param, w.msg, f.Param, f.Message)
+		}
+	}
+}
+
+func TestValidateInputs_Required(t *testing.T) {
+	err := ValidateInputs(validationIntent(), map[string]string{"days": "0"})
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected *ValidationError, got %v", err)
+	}
+	if len(verr.Failures) != 2 || verr.Failures[0].Param != "city" || verr.Failures[1].Param != "days" {
+		t.Errorf("Unexpected failures: %+v", verr.Failures)
+	}
+}
+
+func TestValidateOutputs(t *testing.T) {
+	intent := &parser.Intent{Outputs: []parser.Output{
+		{Name: "summary", Type: "string", Format: "markdown"},
+		{Name: "count", Type: "integer"},
+		{Name: "score", Type: "number"},
+		{Name: "tags", Type: "array"},
+		{Name: "report", Type: "string", Format: "json"},
+		{Name: "link", Type: "string", Format: "uri"},
+		{Name: "data", Type: "json"},
+	}}
+	valid := func() ExecuteResult {
+		return ExecuteResult{
+			"status":  "success",
+			"summary": "# Done",
+			"count":   float64(3),
+			"score":   int64(1),
+			"tags":    []interface{}{"a"},
+			"report":  `{"ok": true}`,
+			"link":    "mailto:team@example.com",
+			"data":    nil,
+		}
+	}
+	if err := ValidateOutputs(intent, valid()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		key   string
+		value interface{}
+		want  string
+	}{
+		{"missing", "summary", nil, "not produced"},
+		{"wrong type", "tags", "a,b", "expected array, got string"},
+		{"null", "count", nil, "expected integer, got null"},
+		{"fractional integer", "count", 2.5, "expected integer, got 2.5"},
+		{"invalid json", "report", "{ok", "expected a JSON document"},
+		{"markdown not a string", "summary", int64(1), "expected string, got integer"},
+		{"relative uri", "link", "/docs", "expected a URI with a scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := valid()
+			results[tt.key] = tt.value
+			if tt.want == "not produced" {
+				delete(results, tt.key)
+			}
+			err := ValidateOutputs(intent, results)
+			var oerr *OutputError
+			if !errors.As(err, &oerr) {
+				t.Fatalf("Expected *OutputError, got %v", err)
+			}
+			if len(oerr.Failures) != 1 || oerr.Failures[0].Param != tt.key || !strings.Contains(oerr.Failures[0].Message, tt.want) {
+				t.Errorf("Expected %s: %q, got %v", tt.key, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestExecute_DeclaredOutputs(t *testing.T) {
+	intent := &parser.Intent{
+		Name: "draft",
+		Outputs: []parser.Output{
+			{Name: "status", Type: "string"},
+			{Name: "total", Type: "integer"},
+			{Name: "items", Type: "array"},
+			{Name: "home", Type: "url"},
+		},
+	}
+	if _, err := Execute(intent, nil, ""); err == nil || !strings.Contains(err.Error(), "no script to produce total, items, home") {
+		t.Errorf("Expected missing script error, got %v", err)
+	}
+
+	result, err := ExecuteWithOptions(intent, nil, Options{Mock: true})
+	if err != nil {
+		t.Fatalf("Mock execution failed: %v", err)
+	}
+	if result["total"] != int64(42) || result["status"] != "success" {
+		t.Errorf("Expected placeholder values, got %v", result)
+	}
+
+	intent.Script = "Total: {{name}}"
+	_, err = Execute(intent, nil, "")
+	if err == nil || !strings.Contains(err.Error(), "3 invalid outputs") {
+		t.Errorf("Expected output errors, got %v", err)
+	}
+}
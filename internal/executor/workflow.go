@@ -0,0 +1,585 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/pack"
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+// logWriter receives the messages of log() steps and console.log
+var logWriter io.Writer = os.Stderr
+
+// workflowRun holds the state of one workflow execution. Every step's
+// result is stored under its id in steps and becomes `result` for the
+// step that follows; named steps are also visible by name.
+type workflowRun struct {
+	ctx      *ExecutionContext
+	context  context.Context // ends when the steps must stop: with the execution, or when a parallel branch is canceled
+	scope    map[string]interface{}
+	steps    map[string]interface{}
+	results  ExecuteResult
+	failures []interface{}
+	done     bool
+	span     int64      // span of the step running, for trace events
+	trace    *stepTrace // what the running plain step reports when it ends
+}
+
+// stepTrace collects what a plain step's end event reports besides its
+// value and error
+type stepTrace struct {
+	args     map[string]interface{}
+	attempts int
+	failure  *StepError // a failure recovered from by on_error
+	handled  string
+}
+
+// StepError reports a workflow step that failed after all its attempts
+type StepError struct {
+	Step     string // step name, step1, step2, ... or its position when nested
+	At       parser.Pos
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	if e.Attempts > 1 {
+		return fmt.Sprintf("%s: %v (after %d attempts)", e.At, e.Err, e.Attempts)
+	}
+	return fmt.Sprintf("%s: %v", e.At, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// executeWorkflowScript executes a workflow script with → commands
+func executeWorkflowScript(ctx *ExecutionContext) (ExecuteResult, error) {
+	steps, err := parser.ParseWorkflow("workflow", ctx.Intent.Script)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckWorkflow(ctx.Intent, steps, Options{Steps: ctx.Steps, Capabilities: ctx.Capabilities}); err != nil {
+		return nil, err
+	}
+
+	run := &workflowRun{
+		ctx:     ctx,
+		context: ctx.Context,
+		scope:   templateScope(ctx),
+		steps:   make(map[string]interface{}),
+		results: make(ExecuteResult),
+		span:    ctx.span,
+	}
+	run.scope["result"] = nil
+	run.scope["steps"] = run.steps
+
+	var shadow error
+	parser.WalkSteps(steps, func(step *parser.Step) {
+		name := step.Name
+		if step.Kind == parser.StepFor || step.Kind == parser.StepTry {
+			name = step.Var
+		}
+		if _, ok := ctx.Values[name]; ok && name != "" && shadow == nil {
+			shadow = fmt.Errorf("%s: step name '%s' shadows an input", step.At, name)
+		}
+	})
+	if shadow != nil {
+		return nil, shadow
+	}
+
+	if err := run.block(steps, true); err != nil {
+		return nil, err
+	}
+
+	// Without a return step the last step's value is the result
+	if !run.done {
+		run.results["result"] = run.scope["result"]
+	}
+
+	// Failures handled by on_error or catch are reported with the results
+	if len(run.failures) > 0 {
+		run.results["errors"] = run.failures
+	}
+
+	// Ensure we have at least a status
+	if run.results["status"] == nil {
+		run.results["status"] = "success"
+	}
+
+	return run.results, nil
+}
+
+// block runs steps in order until one returns. Unnamed top-level steps
+// are stored as step1, step2, ...; steps nested in blocks are only
+// visible by name.
+func (run *workflowRun) block(steps []*parser.Step, top bool) error {
+	for i, step := range steps {
+		if err := run.context.Err(); err != nil {
+			return err
+		}
+		id := step.Name
+		if id == "" && top {
+			id = fmt.Sprintf("step%d", i+1)
+		}
+
+		value, err := run.runStep(step, id)
+		if err != nil {
+			return err
+		}
+		if run.done {
+			return nil
+		}
+
+		if step.Name != "" {
+			run.scope[id] = value
+		}
+		if id != "" {
+			run.steps[id] = value
+		}
+		run.scope["result"] = value
+	}
+	return nil
+}
+
+// runStep executes one step and returns its result, reporting its start
+// and end to the observer. Errors are returned as *StepError.
+func (run *workflowRun) runStep(step *parser.Step, id string) (interface{}, error) {
+	if id == "" {
+		id = step.At.String()
+	}
+	span, parent, trace, prev := run.ctx.newSpan(), run.span, &stepTrace{}, run.trace
+	run.span, run.trace = span, trace
+	defer func() { run.span, run.trace = parent, prev }()
+
+	at := step.At.String()
+	run.ctx.observe(Event{Type: EventStepStart, Span: span, Parent: parent, Step: id, At: at, Expr: step.String()})
+	start := time.Now()
+	value, err := run.execStep(step, id)
+	end := Event{
+		Type:     EventStepEnd,
+		Span:     span,
+		Parent:   parent,
+		Step:     id,
+		At:       at,
+		Inputs:   trace.args,
+		Duration: time.Since(start),
+		Attempts: trace.attempts,
+		Handled:  trace.handled,
+	}
+	switch {
+	case err != nil:
+		end.Error = err.Error()
+	case trace.failure != nil:
+		end.Error, end.Output = trace.failure.Error(), value
+	default:
+		end.Output = value
+	}
+	run.ctx.observe(end)
+	return value, err
+}
+
+// execStep executes one step of any kind
+func (run *workflowRun) execStep(step *parser.Step, id string) (interface{}, error) {
+	switch step.Kind {
+	case parser.StepIf:
+		return run.runIf(step, id)
+	case parser.StepFor:
+		return run.runFor(step, id)
+	case parser.StepTry:
+		return run.runTry(step)
+	case parser.StepParallel:
+		return run.runParallel(step)
+	}
+
+	opts := step.Options
+	var value interface{}
+	var err error
+	attempts := 0
+	for delay := opts.Backoff; ; delay *= 2 {
+		attempts++
+		value, err = run.runExpr(step)
+		if err == nil || attempts > opts.Retry || !run.retryable(err) {
+			break
+		}
+		if sleep(run.context, delay) != nil {
+			break
+		}
+	}
+	run.trace.attempts = attempts
+	if err == nil {
+		return value, nil
+	}
+
+	// A canceled execution stops whatever the step's on_error says
+	failure := &StepError{Step: id, At: step.At, Attempts: attempts, Err: err}
+	if run.context.Err() != nil {
+		return nil, failure
+	}
+	switch opts.OnError {
+	case parser.OnErrorContinue:
+		run.record(failure, parser.OnErrorContinue)
+		return nil, nil
+	case parser.OnErrorFallback:
+		value, err := EvalExpr(opts.Fallback, run.scope)
+		if err != nil {
+			return nil, &StepError{Step: id, At: step.At, Attempts: attempts, Err: fmt.Errorf("fallback: %w", err)}
+		}
+		run.record(failure, parser.OnErrorFallback)
+		return value, nil
+	}
+	return nil, failure
+}
+
+// retryable reports whether another attempt could succeed. Policy
+// violations, expression errors and calls missing from a replayed
+// cassette fail the same way every time, and a canceled execution must
+// stop.
+func (run *workflowRun) retryable(err error) bool {
+	var violation *pack.PolicyViolation
+	var evalErr *EvalError
+	var mismatch *CassetteMismatchError
+	return run.context.Err() == nil && !errors.As(err, &violation) && !errors.As(err, &evalErr) && !errors.As(err, &mismatch)
+}
+
+// sleep waits for d, returning early with ctx's error when it ends
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// record notes a failure that the workflow recovered from
+func (run *workflowRun) record(failure *StepError, handled string) {
+	if handled != "catch" {
+		run.trace.failure, run.trace.handled = failure, handled
+	}
+	run.failures = append(run.failures, map[string]interface{}{
+		"step":     failure.Step,
+		"at":       failure.At.String(),
+		"error":    failure.Err.Error(),
+		"attempts": int64(failure.Attempts),
+		"handled":  handled,
+	})
+}
+
+func (run *workflowRun) runExpr(step *parser.Step) (interface{}, error) {
+	call, ok := step.Expr.(*parser.CallExpr)
+	if ok {
+		name := stepName(call)
+		if name == "return" {
+			return nil, run.doReturn(call)
+		}
+		if h, ok := run.ctx.Steps.Lookup(name); ok {
+			return run.runHandler(call, h, step.Options.Timeout)
+		}
+	}
+	return EvalExpr(step.Expr, run.scope)
+}
+
+// runIf runs the first branch whose condition holds. Its value is the
+// result of that branch's last step; when no branch runs, the previous
+// result passes through unchanged.
+func (run *workflowRun) runIf(step *parser.Step, id string) (interface{}, error) {
+	cond, err := EvalExpr(step.Expr, run.scope)
+	if err != nil {
+		return nil, &StepError{Step: id, At: step.At, Attempts: 1, Err: err}
+	}
+	branch := step.Else
+	if truthy(cond) {
+		branch = step.Body
+	}
+	if err := run.block(branch, false); err != nil {
+		return nil, err
+	}
+	return run.scope["result"], nil
+}
+
+// runFor runs the loop body once per element of an array, with the loop
+// variable bound to the element. Its value is the list of each
+// iteration's last result.
+func (run *workflowRun) runFor(step *parser.Step, id string) (interface{}, error) {
+	value, err := EvalExpr(step.Expr, run.scope)
+	if err == nil {
+		if _, ok := value.([]interface{}); !ok {
+			err = fmt.Errorf("for loop expects an array, got %s", typeName(value))
+		}
+	}
+	if err != nil {
+		return nil, &StepError{Step: id, At: step.At, Attempts: 1, Err: err}
+	}
+
+	items := value.([]interface{})
+	results := make([]interface{}, 0, len(items))
+	defer delete(run.scope, step.Var)
+	for _, item := range items {
+		run.scope[step.Var] = item
+		if err := run.block(step.Body, false); err != nil {
+			return nil, err
+		}
+		if run.done {
+			return nil, nil
+		}
+		results = append(results, run.scope["result"])
+	}
+	return results, nil
+}
+
+// runTry runs the try block, and the catch block if a step in it fails.
+// The catch variable holds the failed step's id, error message and
+// number of attempts. Its value is the result of the block that ran last.
+func (run *workflowRun) runTry(step *parser.Step) (interface{}, error) {
+	err := run.block(step.Body, false)
+	if err == nil {
+		return run.scope["result"], nil
+	}
+	var failure *StepError
+	if !errors.As(err, &failure) || run.context.Err() != nil {
+		return nil, err
+	}
+	run.record(failure, "catch")
+
+	if step.Var != "" {
+		run.scope[step.Var] = map[string]interface{}{
+			"step":     failure.Step,
+			"message":  failure.Err.Error(),
+			"attempts": int64(failure.Attempts),
+		}
+		defer delete(run.scope, step.Var)
+	}
+	if err := run.block(step.Else, false); err != nil {
+		return nil, err
+	}
+	return run.scope["result"], nil
+}
+
+// runParallel runs each step of the block as a branch, at most
+// step.Options.Limit of them at once. Branches see the results of earlier
+// steps but not each other's. Once all have finished, the results of their
+// named steps become visible by name and make up the parallel step's value.
+// The first branch to fail cancels the others, unless fail_fast=false lets
+// them finish; the first failure in source order is then returned.
+func (run *workflowRun) runParallel(step *parser.Step) (interface{}, error) {
+	branches := step.Body
+	limit := step.Options.Limit
+	if limit <= 0 || limit > len(branches) {
+		limit = len(branches)
+	}
+	ctx, cancel := context.WithCancel(run.context)
+	defer cancel()
+
+	runs := make([]*workflowRun, len(branches))
+	errs := make([]error, len(branches))
+	var first error // the failure that canceled the other branches
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, limit)
+	for i, branch := range branches {
+		runs[i] = run.branch(ctx)
+		wg.Add(1)
+		go func(i int, branch *parser.Step) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+			errs[i] = runs[i].block([]*parser.Step{branch}, false)
+			if errs[i] != nil && step.Options.FailFast {
+				mu.Lock()
+				if first == nil {
+					first = errs[i]
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}(i, branch)
+	}
+	wg.Wait()
+
+	values := make(map[string]interface{})
+	for i, branch := range branches {
+		run.failures = append(run.failures, runs[i].failures...)
+		if first == nil && errs[i] != nil {
+			first = errs[i]
+		}
+		parser.WalkSteps([]*parser.Step{branch}, func(s *parser.Step) {
+			if value, ok := runs[i].scope[s.Name]; ok && s.Name != "" {
+				values[s.Name] = value
+			}
+		})
+	}
+	if first != nil {
+		return nil, first
+	}
+	for name, value := range values {
+		run.scope[name] = value
+		run.steps[name] = value
+	}
+	return values, nil
+}
+
+// branch returns a run for a parallel branch that stops with ctx. It has
+// its own copy of the scope, so that branches do not see each other's
+// results, and reports its steps as children of the parallel step.
+func (run *workflowRun) branch(ctx context.Context) *workflowRun {
+	child := &workflowRun{
+		ctx:     run.ctx,
+		context: ctx,
+		scope:   make(map[string]interface{}, len(run.scope)),
+		steps:   make(map[string]interface{}, len(run.steps)),
+		results: make(ExecuteResult),
+		span:    run.span,
+	}
+	for name, value := range run.scope {
+		child.scope[name] = value
+	}
+	for id, value := range run.steps {
+		child.steps[id] = value
+	}
+	child.scope["steps"] = child.steps
+	return child
+}
+
+// stepName returns the name a call refers to, such as "log" or
+// "http.get", or "" when the callee is not a plain or dotted name
+func stepName(call *parser.CallExpr) string {
+	switch fun := call.Fun.(type) {
+	case *parser.Ident:
+		return fun.Name
+	case *parser.MemberExpr:
+		if x, ok := fun.X.(*parser.Ident); ok {
+			return x.Name + "." + fun.Name
+		}
+	}
+	return ""
+}
+
+// runHandler evaluates a step's arguments, checks them and the required
+// capabilities against its spec, and runs it within timeout, if set
+func (run *workflowRun) runHandler(call *parser.CallExpr, h StepHandler, timeout time.Duration) (interface{}, error) {
+	spec := h.Spec()
+	if missing := missingCapabilities(spec, run.ctx.Capabilities); len(missing) > 0 {
+		return nil, &pack.PolicyViolation{
+			Policy: "capabilities",
+			Reason: fmt.Sprintf("step %s requires %s, which the package does not declare", spec.Name, strings.Join(missing, ", ")),
+		}
+	}
+
+	f := stepFunction(spec)
+	if n := len(call.Args); n < f.minArgs || n > f.maxArgs {
+		return nil, fmt.Errorf("%s expects %s, got %d", spec.Name, arity(f), n)
+	}
+	args := make([]interface{}, len(call.Args))
+	for i, arg := range call.Args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("%s: named argument '%s' is not supported", spec.Name, arg.Name)
+		}
+		value, err := EvalExpr(arg.Value, run.scope)
+		if err != nil {
+			return nil, err
+		}
+		if want, got := f.sig.arg(i), typeName(value); !accepts(want, got) || (got == "null" && want != "any") {
+			return nil, fmt.Errorf("%s: argument '%s' must be %s, got %s", spec.Name, spec.Args[i].Name, strings.ReplaceAll(want, "|", " or "), got)
+		}
+		args[i] = value
+	}
+	run.trace.args = make(map[string]interface{}, len(args))
+	for i, value := range args {
+		run.trace.args[spec.Args[i].Name] = value
+	}
+
+	// A step abandoned by its timeout may still be running when run.span
+	// moves on to the next step
+	span := run.span
+	result, err := withTimeout(run.context, timeout, func(ctx context.Context) (interface{}, error) {
+		return h.Run(&StepCall{
+			Context:  ctx,
+			Step:     spec.Name,
+			Args:     args,
+			Inputs:   run.ctx.Values,
+			Network:  run.ctx.Network,
+			Log:      run.ctx.secrets.writer(logWriter),
+			Cassette: run.ctx.Cassette,
+			exec:     run.ctx,
+			span:     span,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if want, got := f.sig.returns, typeName(result); !accepts(want, got) {
+		return nil, fmt.Errorf("%s returned %s, but declares %s", spec.Name, got, strings.ReplaceAll(want, "|", " or "))
+	}
+	return result, nil
+}
+
+// doReturn maps `return(key=value, ...)` onto the results and ends the
+// workflow. A single positional object argument is merged the same way.
+func (run *workflowRun) doReturn(call *parser.CallExpr) error {
+	for _, arg := range call.Args {
+		value, err := EvalExpr(arg.Value, run.scope)
+		if err != nil {
+			return err
+		}
+		if arg.Name != "" {
+			run.results[arg.Name] = value
+			continue
+		}
+		obj, ok := value.(map[string]interface{})
+		if !ok || len(call.Args) != 1 {
+			return fmt.Errorf("return arguments must be named, as in return(status=\"ok\"), or a single object")
+		}
+		for key, v := range obj {
+			run.results[key] = v
+		}
+	}
+	run.done = true
+	return nil
+}
+
+// withTimeout runs fn with a context that ends after timeout, unless it
+// is zero, or when ctx does. A step that does not return by then is
+// abandoned and finishes in the background.
+func withTimeout(ctx context.Context, timeout time.Duration, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	stepCtx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		stepCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	type outcome struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn(stepCtx)
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && ctx.Err() == nil && stepCtx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", timeout)
+		}
+		return o.value, o.err
+	case <-stepCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+}
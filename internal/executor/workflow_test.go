@@ -0,0 +1,455 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/intentregistry/intent-cli/internal/parser"
+)
+
+func init() {
+	logWriter = io.Discard
+}
+
+func workflowIntent(script string) *parser.Intent {
+	return &parser.Intent{
+		Name: "workflow",
+		Parameters: []parser.Parameter{
+			{Name: "text", Type: "string", Required: true},
+			{Name: "limit", Type: "integer", Default: int64(2)},
+		},
+		Script: script,
+	}
+}
+
+func TestExecute_WorkflowChaining(t *testing.T) {
+	script := `→ log("{text | upper}")
+→ words = split(text, " ")
+→ result | length
+→ return(status="ok", count=result, first=words[0], logged=steps.step1, over=steps.step3 > input.limit)`
+
+	result, err := Execute(workflowIntent(script), map[string]string{"text": "one two three"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := ExecuteResult{
+		"status": "ok",
+		"count":  int64(3),
+		"first":  "one",
+		"logged": "ONE TWO THREE",
+		"over":   true,
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %v, got %v", want, result)
+	}
+}
+
+func TestExecute_WorkflowWithoutReturn(t *testing.T) {
+	result, err := Execute(workflowIntent(`→ split(text, ",")
+→ join(result, "+")`), map[string]string{"text": "a,b"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["result"] != "a+b" || result["status"] != "success" {
+		t.Errorf("Expected last step result and default status, got %v", result)
+	}
+}
+
+func TestExecute_WorkflowReturnEndsWorkflow(t *testing.T) {
+	script := `→ return({ status: "early", text: text })
+→ log("{missing}")`
+	result, err := Execute(workflowIntent(script), map[string]string{"text": "x"}, "")
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if result["status"] != "early" || result["text"] != "x" {
+		t.Errorf("Unexpected results: %v", result)
+	}
+}
+
+func TestExecute_WorkflowControlFlow(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		text   string
+		want   ExecuteResult
+	}{
+		{
+			name: "if taken",
+			script: `→ if text == "yes":
+  → "agreed"
+else:
+  → "refused"
+→ return(answer=result)`,
+			text: "yes",
+			want: ExecuteResult{"status": "success", "answer": "agreed"},
+		},
+		{
+			name: "else if",
+			script: `→ if text == "yes":
+  → "agreed"
+else if text == "maybe":
+  → "undecided"
+else:
+  → "refused"
+→ return(answer=result)`,
+			text: "maybe",
+			want: ExecuteResult{"status": "success", "answer": "undecided"},
+		},
+		{
+			name: "if not taken keeps result",
+			script: `→ upper(text)
+→ if text == "":
+  → "empty"
+→ return(answer=result)`,
+			text: "abc",
+			want: ExecuteResult{"status": "success", "answer": "ABC"},
+		},
+		{
+			name: "for collects results",
+			script: `→ words = split(text, " ")
+→ for word in words:
+  → w = upper(word)
+  → w + "!"
+→ return(shouted=result, last=w)`,
+			text: "a b",
+			want: ExecuteResult{"status": "success", "shouted": []interface{}{"A!", "B!"}, "last": "B"},
+		},
+		{
+			name: "early return from loop",
+			script: `→ for word in split(text, " "):
+  → if word == "stop":
+    → return(status="stopped", before=result)
+  → word
+→ return(status="finished")`,
+			text: "go stop go",
+			want: ExecuteResult{"status": "stopped", "before": "go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Execute(workflowIntent(tt.script), map[string]string{"text": tt.text}, "")
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, result)
+			}
+		})
+	}
+}
+
+func TestExecute_WorkflowErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"undefined variable", `→ log("{txt}")`, "workflow:1:1: txt: undefined variable 'txt'"},
+		{"missing step", `→ steps.missing + 1`, "cannot apply '+' to null and integer"},
+		{"shadowed input", `→ text = "x"`, "step name 'text' shadows an input"},
+		{"reserved name", `→ result = 1`, `step name "result" is reserved`},
+		{"duplicate name", "→ a = 1\n→ a = 2", `duplicate step name "a"`},
+		{"positional return", `→ return("ok")`, "return arguments must be named"},
+		{"unknown function", "→ log(\"start\")\n→ file.read(text)", "workflow:2:12: file.read(text): unknown function 'file.read'"},
+		{"loop over string", "→ for c in text:\n  → c", "workflow:1:12: text: for loop expects an array, got string"},
+		{"shadowing loop variable", "→ for text in [1]:\n  → text", "step name 'text' shadows an input"},
+		{"nested step error", "→ if true:\n  → log(\"{nope}\")", "workflow:2:3: nope: undefined variable 'nope'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Execute(workflowIntent(tt.script), map[string]string{"text": "x"}, "")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// failingSteps returns a registry with `flaky(n)`, which fails until it
+// has been called n times, and `slow()`, which never finishes in time
+func failingSteps() (*Registry, *int) {
+	calls := 0
+	registry := NewRegistry()
+	registry.Register(NewStep(StepSpec{Name: "flaky", Args: []ArgSpec{{Name: "n", Type: "integer"}}}, func(call *StepCall) (interface{}, error) {
+		calls++
+		if int64(calls) < call.Args[0].(int64) {
+			return nil, fmt.Errorf("service unavailable")
+		}
+		return "ok", nil
+	}))
+	registry.Register(NewStep(StepSpec{Name: "slow"}, func(call *StepCall) (interface{}, error) {
+		time.Sleep(time.Second)
+		return "late", nil
+	}))
+	return registry, &calls
+}
+
+func TestExecute_WorkflowStepFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		calls  int
+		want   ExecuteResult
+	}{
+		{
+			name:   "retry until success",
+			script: `→ flaky(3) retry=2 backoff="1ms"`,
+			calls:  3,
+			want:   ExecuteResult{"status": "success", "result": "ok"},
+		},
+		{
+			name:   "continue",
+			script: "→ flaky(5) retry=1 backoff=\"1ms\" on_error=continue\n→ return(value=result)",
+			calls:  2,
+			want: ExecuteResult{"status": "success", "value": nil, "errors": []interface{}{map[string]interface{}{
+				"step": "step1", "at": "workflow:1:1", "error": "service unavailable", "attempts": int64(2), "handled": "continue",
+			}}},
+		},
+		{
+			name:   "fallback",
+			script: "→ data = flaky(5) on_error=fallback({ cached: true })\n→ return(cached=data.cached)",
+			calls:  1,
+			want: ExecuteResult{"status": "success", "cached": true, "errors": []interface{}{map[string]interface{}{
+				"step": "data", "at": "workflow:1:1", "error": "service unavailable", "attempts": int64(1), "handled": "fallback",
+			}}},
+		},
+		{
+			name:   "timeout",
+			script: "→ slow() timeout=\"10ms\" on_error=fallback(\"gave up\")",
+			want: ExecuteResult{"status": "success", "result": "gave up", "errors": []interface{}{map[string]interface{}{
+				"step": "step1", "at": "workflow:1:1", "error": "timed out after 10ms", "attempts": int64(1), "handled": "fallback",
+			}}},
+		},
+		{
+			name: "try and catch",
+			script: `→ try:
+  → log("fetching")
+  → flaky(5)
+catch err:
+  → "failed in {err.step}: {err.message}"
+→ return(message=result)`,
+			calls: 1,
+			want: ExecuteResult{"status": "success", "message": "failed in workflow:3:3: service unavailable", "errors": []interface{}{map[string]interface{}{
+				"step": "workflow:3:3", "at": "workflow:3:3", "error": "service unavailable", "attempts": int64(1), "handled": "catch",
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, calls := failingSteps()
+			result, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": "x"}, Options{Steps: registry})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, result)
+			}
+			if *calls != tt.calls {
+				t.Errorf("Expected %d calls, got %d", tt.calls, *calls)
+			}
+		})
+	}
+}
+
+func TestExecute_WorkflowStepFailureErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{"retries exhausted", `→ flaky(5) retry=2 backoff="1ms"`, "workflow:1:1: service unavailable (after 3 attempts)"},
+		{"expression errors are not retried", `→ steps.missing + 1 retry=3`, "cannot apply '+' to null and integer"},
+		{"error in catch block", "→ try:\n  → flaky(5)\ncatch:\n  → flaky(9)", "workflow:4:3: service unavailable"},
+		{"unknown attribute", `→ flaky(1) retries=2`, `unknown step attribute "retries"`},
+		{"bad on_error", `→ flaky(1) on_error=ignore`, `"on_error" must be continue, fail or fallback(value)`},
+		{"try without catch", "→ try:\n  → flaky(1)", "try without a matching catch"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, _ := failingSteps()
+			_, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": "x"}, Options{Steps: registry})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+			var stepErr *StepError
+			if errors.As(err, &stepErr) && strings.HasPrefix(tt.name, "expression") && stepErr.Attempts != 1 {
+				t.Errorf("Expected 1 attempt, got %d", stepErr.Attempts)
+			}
+		})
+	}
+}
+
+func TestExecuteContext_Cancellation(t *testing.T) {
+	registry, _ := failingSteps()
+	registry.Register(NewStep(StepSpec{Name: "wait"}, func(call *StepCall) (interface{}, error) {
+		<-call.Context.Done()
+		return nil, call.Context.Err()
+	}))
+
+	tests := []struct {
+		name   string
+		script string
+		want   error
+	}{
+		{"deadline during step", `→ wait()`, ErrTimeout},
+		{"deadline during backoff", `→ flaky(5) retry=3 backoff="1h"`, ErrTimeout},
+		{"deadline during abandoned step", `→ slow()`, ErrTimeout},
+		{"not caught", "→ try:\n  → wait()\ncatch:\n  → \"caught\"", ErrTimeout},
+		{"not continued", "→ wait() on_error=continue\n→ \"continued\"", ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := ExecuteContext(ctx, workflowIntent(tt.script), map[string]string{"text": "x"}, Options{Steps: registry})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected execution to stop at the deadline, took %v", elapsed)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ExecuteContext(ctx, workflowIntent(`→ log("never")`), map[string]string{"text": "x"}, Options{}); !errors.Is(err, ErrCanceled) {
+		t.Errorf("Expected ErrCanceled, got %v", err)
+	}
+}
+
+// parallelSteps returns a registry with `fetch(name)`, which returns name
+// after a short delay, `fail()`, which fails at once, and `wait()`, which
+// blocks until canceled. It also returns the most fetches seen running at
+// once and the number that finished.
+func parallelSteps() (*Registry, *int64, *int64) {
+	var mu sync.Mutex
+	var running, most, finished int64
+	registry := NewRegistry()
+	registry.Register(NewStep(StepSpec{Name: "fetch", Args: []ArgSpec{{Name: "name", Type: "string"}}}, func(call *StepCall) (interface{}, error) {
+		mu.Lock()
+		running++
+		if running > most {
+			most = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		finished++
+		mu.Unlock()
+		return call.Args[0], nil
+	}))
+	registry.Register(NewStep(StepSpec{Name: "fail"}, func(call *StepCall) (interface{}, error) {
+		return nil, fmt.Errorf("service unavailable")
+	}))
+	registry.Register(NewStep(StepSpec{Name: "wait"}, func(call *StepCall) (interface{}, error) {
+		<-call.Context.Done()
+		return nil, call.Context.Err()
+	}))
+	return registry, &most, &finished
+}
+
+func TestExecute_Parallel(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		most   int64
+		want   ExecuteResult
+	}{
+		{
+			name: "merges named results",
+			script: `→ r = parallel:
+  → a = fetch("a")
+  → b = fetch(text)
+  → fetch("unnamed")
+→ return(a=a, b=b, all=r)`,
+			most: 3,
+			want: ExecuteResult{"status": "success", "a": "a", "b": "x", "all": map[string]interface{}{"a": "a", "b": "x"}},
+		},
+		{
+			name: "limit",
+			script: `→ parallel limit=1:
+  → a = fetch("a")
+  → b = fetch("b")
+→ return(both=a + b)`,
+			most: 1,
+			want: ExecuteResult{"status": "success", "both": "ab"},
+		},
+		{
+			name: "branches run their steps in order",
+			script: `→ parallel:
+  → if true:
+    → first = fetch("1")
+    → second = fetch(first + "2")
+  → other = fetch("3")
+→ return(value=second + other)`,
+			most: 2,
+			want: ExecuteResult{"status": "success", "value": "123"},
+		},
+		{
+			name: "recovered branch failures",
+			script: `→ parallel:
+  → a = fail() on_error=fallback("cached")
+  → b = fetch("b")
+→ return(a=a, b=b)`,
+			most: 1,
+			want: ExecuteResult{"status": "success", "a": "cached", "b": "b", "errors": []interface{}{map[string]interface{}{
+				"step": "a", "at": "workflow:2:3", "error": "service unavailable", "attempts": int64(1), "handled": "fallback",
+			}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, most, _ := parallelSteps()
+			result, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": "x"}, Options{Steps: registry})
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, result)
+			}
+			if *most != tt.most {
+				t.Errorf("Expected at most %d fetches at once, got %d", tt.most, *most)
+			}
+		})
+	}
+}
+
+func TestExecute_ParallelFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		finished int64
+	}{
+		{"cancels siblings", "→ parallel:\n  → wait()\n  → fail()", 0},
+		{"fail_fast=false lets siblings finish", "→ parallel fail_fast=false:\n  → fail()\n  → fetch(\"a\")\n  → fetch(\"b\")", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, _, finished := parallelSteps()
+			start := time.Now()
+			_, err := ExecuteWithOptions(workflowIntent(tt.script), map[string]string{"text": "x"}, Options{Steps: registry})
+			var stepErr *StepError
+			if !errors.As(err, &stepErr) || !strings.Contains(err.Error(), "service unavailable") {
+				t.Fatalf("Expected the failure of fail(), got %v", err)
+			}
+			if *finished != tt.finished {
+				t.Errorf("Expected %d fetches to finish, got %d", tt.finished, *finished)
+			}
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("Expected the failure to stop the execution, took %v", elapsed)
+			}
+		})
+	}
+}
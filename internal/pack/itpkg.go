@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strings"
 )
@@ -201,6 +202,44 @@ func ReadItpkgManifest(path string) (*ItpkgManifest, error) {
 	return &manifest, nil
 }
 
+// FindManifest looks for the itpkg.json of the package containing
+// itmlFile, searching its directory and then each parent. It returns nil
+// when the intent is not part of a package.
+func FindManifest(itmlFile string) (*ItpkgManifest, string, error) {
+	dir, err := filepath.Abs(filepath.Dir(itmlFile))
+	if err != nil {
+		return nil, "", err
+	}
+	for {
+		manifestPath := filepath.Join(dir, "itpkg.json")
+		if _, err := os.Stat(manifestPath); err == nil {
+			manifest, err := ReadItpkgManifest(manifestPath)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to read %s: %w", manifestPath, err)
+			}
+			return manifest, manifestPath, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, "", nil
+		}
+		dir = parent
+	}
+}
+
+var nameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._@-]+`)
+
+// SanitizeName makes a package name usable as a file name
+func SanitizeName(s string) string {
+	return nameSanitizer.ReplaceAllString(s, "-")
+}
+
+// PackageDir returns the directory the package name is installed to
+// under dest, such as intents/@scope-name for @scope/name
+func PackageDir(dest, name string) string {
+	return filepath.Join(dest, SanitizeName(name))
+}
+
 // ValidateManifest validates the manifest structure
 func ValidateManifest(manifest *ItpkgManifest, srcDir string) error {
 	if manifest.Name == "" {
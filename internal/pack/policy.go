@@ -0,0 +1,260 @@
+package pack
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"time"
+)
+
+// Defaults applied to outbound requests when a policy leaves them unset
+const (
+	DefaultNetworkTimeout = 30 * time.Second
+	DefaultMaxBodySize    = 10 << 20
+)
+
+// Defaults applied to scripts when a policy leaves them unset
+const (
+	DefaultScriptTimeout   = 10 * time.Second
+	DefaultScriptCPUTime   = 5 * time.Second
+	DefaultScriptMaxMemory = 64 << 20
+	DefaultScriptMaxOutput = 10 << 20
+)
+
+// NetworkPolicy is the outbound network policy of a package, read from
+// policies.security.network.outbound in itpkg.json:
+//
+//	"outbound": {
+//	  "allow": ["api.example.com", "*.example.org:443"],
+//	  "deny": ["*"],
+//	  "methods": ["GET"],
+//	  "maxBodySize": 1048576,
+//	  "timeout": "10s"
+//	}
+//
+// Host patterns are globs with an optional port. The most specific
+// matching pattern decides, deny wins ties, and hosts that match no
+// pattern are denied.
+type NetworkPolicy struct {
+	Allow       []string
+	Deny        []string
+	Methods     []string // empty allows every method
+	MaxBodySize int64
+	Timeout     time.Duration
+}
+
+// ScriptPolicy limits the scripts of a package, read from
+// policies.security.scripts in itpkg.json:
+//
+//	"scripts": {
+//	  "timeout": "5s",
+//	  "cpuTime": "2s",
+//	  "maxMemory": 33554432,
+//	  "maxOutput": 1048576
+//	}
+//
+// Timeout bounds how long a script runs in wall-clock time, waiting
+// included. A javascript: script runs in a worker process of its own:
+// CPUTime bounds the CPU time of that process (zero means no limit), and
+// MaxMemory how much its heap may grow. MaxOutput bounds how much a
+// subprocess script may write to stdout or stderr. Sizes are in bytes.
+type ScriptPolicy struct {
+	Timeout   time.Duration
+	CPUTime   time.Duration
+	MaxMemory int64
+	MaxOutput int64
+}
+
+// DefaultScriptPolicy returns the limits for scripts outside a package
+func DefaultScriptPolicy() *ScriptPolicy {
+	return &ScriptPolicy{
+		Timeout:   DefaultScriptTimeout,
+		CPUTime:   DefaultScriptCPUTime,
+		MaxMemory: DefaultScriptMaxMemory,
+		MaxOutput: DefaultScriptMaxOutput,
+	}
+}
+
+// PolicyViolation reports an operation a package policy does not permit
+type PolicyViolation struct {
+	Policy string
+	Reason string
+}
+
+func (e *PolicyViolation) Error() string {
+	return fmt.Sprintf("policy violation (%s): %s", e.Policy, e.Reason)
+}
+
+// NetworkPolicy returns the manifest's outbound network policy. A manifest
+// without one denies all outbound traffic.
+func (m *ItpkgManifest) NetworkPolicy() (*NetworkPolicy, error) {
+	policy := &NetworkPolicy{MaxBodySize: DefaultMaxBodySize, Timeout: DefaultNetworkTimeout}
+
+	security, _ := m.Policies["security"].(map[string]interface{})
+	network, _ := security["network"].(map[string]interface{})
+	outbound, _ := network["outbound"].(map[string]interface{})
+
+	var err error
+	if policy.Allow, err = stringList(outbound, "allow"); err != nil {
+		return nil, err
+	}
+	if policy.Deny, err = stringList(outbound, "deny"); err != nil {
+		return nil, err
+	}
+	if policy.Methods, err = stringList(outbound, "methods"); err != nil {
+		return nil, err
+	}
+
+	if err := sizeField(outbound, "policies.security.network.outbound", "maxBodySize", &policy.MaxBodySize); err != nil {
+		return nil, err
+	}
+	if err := durationField(outbound, "policies.security.network.outbound", "timeout", &policy.Timeout); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// ScriptPolicy returns the manifest's limits for scripts, with defaults
+// for those it leaves unset
+func (m *ItpkgManifest) ScriptPolicy() (*ScriptPolicy, error) {
+	policy := DefaultScriptPolicy()
+
+	security, _ := m.Policies["security"].(map[string]interface{})
+	scripts, _ := security["scripts"].(map[string]interface{})
+
+	if err := sizeField(scripts, "policies.security.scripts", "maxMemory", &policy.MaxMemory); err != nil {
+		return nil, err
+	}
+	if err := sizeField(scripts, "policies.security.scripts", "maxOutput", &policy.MaxOutput); err != nil {
+		return nil, err
+	}
+	if err := durationField(scripts, "policies.security.scripts", "timeout", &policy.Timeout); err != nil {
+		return nil, err
+	}
+	if err := durationField(scripts, "policies.security.scripts", "cpuTime", &policy.CPUTime); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// sizeField reads a positive number of bytes, leaving target unchanged
+// when the key is absent
+func sizeField(obj map[string]interface{}, path, key string, target *int64) error {
+	switch v := obj[key].(type) {
+	case nil:
+	case float64:
+		if v <= 0 {
+			return fmt.Errorf("%s.%s must be positive", path, key)
+		}
+		*target = int64(v)
+	default:
+		return fmt.Errorf("%s.%s must be a number of bytes", path, key)
+	}
+	return nil
+}
+
+// durationField reads a positive duration given as a string such as "10s"
+// or a number of seconds, leaving target unchanged when the key is absent
+func durationField(obj map[string]interface{}, path, key string, target *time.Duration) error {
+	switch v := obj[key].(type) {
+	case nil:
+		return nil
+	case float64:
+		*target = time.Duration(v * float64(time.Second))
+	case string:
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", path, key, err)
+		}
+		*target = d
+	default:
+		return fmt.Errorf("%s.%s must be a duration such as \"10s\"", path, key)
+	}
+	if *target <= 0 {
+		return fmt.Errorf("%s.%s must be positive", path, key)
+	}
+	return nil
+}
+
+func stringList(obj map[string]interface{}, key string) ([]string, error) {
+	raw, ok := obj[key]
+	if !ok {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("policies.security.network.outbound.%s must be a list of strings", key)
+	}
+	list := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("policies.security.network.outbound.%s must be a list of strings", key)
+		}
+		list[i] = s
+	}
+	return list, nil
+}
+
+// CheckRequest returns a *PolicyViolation unless the policy permits method
+// requests to host, given as host or host:port
+func (p *NetworkPolicy) CheckRequest(method, host string) error {
+	if len(p.Methods) > 0 {
+		allowed := false
+		for _, m := range p.Methods {
+			if strings.EqualFold(m, method) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &PolicyViolation{
+				Policy: "network.outbound.methods",
+				Reason: fmt.Sprintf("method %s is not allowed (allowed: %s)", method, strings.Join(p.Methods, ", ")),
+			}
+		}
+	}
+
+	allow := bestMatch(p.Allow, host)
+	deny := bestMatch(p.Deny, host)
+	if allow < 0 || deny >= allow {
+		return &PolicyViolation{
+			Policy: "network.outbound",
+			Reason: fmt.Sprintf("host %s is not allowed", host),
+		}
+	}
+	return nil
+}
+
+// bestMatch returns the specificity of the most specific pattern matching
+// host, or -1 if none does. Specificity counts the literal characters of
+// the pattern, with a port adding one more.
+func bestMatch(patterns []string, host string) int {
+	hostname, port := splitHostPort(host)
+	best := -1
+	for _, pattern := range patterns {
+		pname, pport := splitHostPort(pattern)
+		if pport != "" && pport != port {
+			continue
+		}
+		if ok, _ := path.Match(strings.ToLower(pname), strings.ToLower(hostname)); !ok {
+			continue
+		}
+		score := len(strings.NewReplacer("*", "", "?", "").Replace(pname))
+		if pport != "" {
+			score++
+		}
+		if score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+func splitHostPort(s string) (string, string) {
+	if host, port, err := net.SplitHostPort(s); err == nil {
+		return host, port
+	}
+	return s, ""
+}
@@ -0,0 +1,493 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// File is the AST of an ITML DSL document
+type File struct {
+	Intent   *IntentDecl
+	Meta     []*MetaEntry
+	Inputs   *Section
+	Outputs  *Section
+	Workflow *Section
+}
+
+// IntentDecl is the `intent "Name"` declaration
+type IntentDecl struct {
+	At   Pos
+	Name string
+}
+
+// MetaEntry is a top-level `key: value` pair such as `description: "..."`
+type MetaEntry struct {
+	At    Pos
+	Key   string
+	Value Expr
+}
+
+// Section is a named block (`inputs:`, `outputs:`, `workflow:`)
+type Section struct {
+	At     Pos
+	Name   string
+	Params []*ParamDecl
+	Steps  []*Step
+}
+
+// ParamDecl declares an input or output: `- name (type) attr attr=value ...`
+type ParamDecl struct {
+	At     Pos
+	Name   string
+	Type   string
+	TypeAt Pos
+	Attrs  []*Attr
+}
+
+// Attr returns the first attribute with the given key, or nil
+func (d *ParamDecl) Attr(key string) *Attr {
+	for _, a := range d.Attrs {
+		if a.Key == key {
+			return a
+		}
+	}
+	return nil
+}
+
+// Attr is a declaration or step attribute. Value is nil for bare flags
+// like `required`.
+type Attr struct {
+	At    Pos
+	Key   string
+	Value Expr
+}
+
+// StepKind distinguishes plain steps from control flow
+type StepKind int
+
+const (
+	StepExpr     StepKind = iota // `→ expr` or `→ name = expr`
+	StepIf                       // `→ if cond:` with Body and optional Else
+	StepFor                      // `→ for var in expr:` with Body
+	StepTry                      // `→ try:` with Body and a `catch:` block in Else
+	StepParallel                 // `→ parallel:` whose Body steps run concurrently
+)
+
+// Step is a single `→ expr` workflow line. Name is set when the step
+// binds its result with `→ name = expr`. Control-flow steps own the
+// steps indented below them: for StepIf, Expr is the condition and Else
+// holds the `else:` branch (a single StepIf for `else if`); for StepFor,
+// Var is bound to each element of Expr in turn; for StepTry, Else holds
+// the `catch:` block and Var, when set, names the caught error; for
+// StepParallel, each step of Body is a branch.
+//
+// Plain steps may end with attributes that control failures, as in
+// `→ http.get(url) retry=3 timeout="10s"`, and parallel steps attributes
+// that control their branches, as in `→ parallel limit=2:`; Options holds
+// their values.
+type Step struct {
+	At      Pos
+	Kind    StepKind
+	Name    string
+	Var     string
+	Expr    Expr
+	Attrs   []*Attr
+	Options StepOptions
+	Body    []*Step
+	Else    []*Step
+}
+
+// String renders the step's own line in script form, without the leading
+// arrow or the steps nested below it
+func (s *Step) String() string {
+	switch s.Kind {
+	case StepIf:
+		return "if " + s.Expr.String() + ":"
+	case StepFor:
+		return "for " + s.Var + " in " + s.Expr.String() + ":"
+	case StepTry:
+		return "try:"
+	}
+	var b strings.Builder
+	if s.Name != "" {
+		b.WriteString(s.Name + " = ")
+	}
+	if s.Kind == StepParallel {
+		b.WriteString("parallel")
+	} else {
+		b.WriteString(s.Expr.String())
+	}
+	for _, a := range s.Attrs {
+		b.WriteString(" " + a.Key)
+		if a.Value != nil {
+			b.WriteString("=" + a.Value.String())
+		}
+	}
+	if s.Kind == StepParallel {
+		b.WriteString(":")
+	}
+	return b.String()
+}
+
+// DefaultRetryBackoff is the delay before the first retry of a step that
+// sets retry but not backoff. Each further retry waits twice as long.
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// Values of StepOptions.OnError
+const (
+	OnErrorFail     = "fail"     // stop the workflow (the default)
+	OnErrorContinue = "continue" // use null as the step's value
+	OnErrorFallback = "fallback" // use the value of Fallback
+)
+
+// StepOptions control how a plain step fails:
+//
+//	→ data = http.get(url) retry=3 backoff="1s" timeout="10s" on_error=fallback({})
+//
+// and how a parallel step runs its branches:
+//
+//	→ parallel limit=2 fail_fast=false:
+type StepOptions struct {
+	Retry    int           // extra attempts after the first failure
+	Backoff  time.Duration // delay before the first retry
+	Timeout  time.Duration // limit on each attempt; zero means none
+	OnError  string        // one of the OnError constants
+	Fallback Expr          // value used by on_error=fallback(expr)
+	Limit    int           // branches running at once; zero means all
+	FailFast bool          // cancel the other branches when one fails
+}
+
+// FormatWorkflow renders steps as a workflow script, one `→ step` per line
+// with nested blocks indented by two spaces
+func FormatWorkflow(steps []*Step) string {
+	var lines []string
+	formatSteps(steps, "", &lines)
+	return strings.Join(lines, "\n")
+}
+
+func formatSteps(steps []*Step, indent string, lines *[]string) {
+	for _, s := range steps {
+		*lines = append(*lines, indent+"→ "+s.String())
+		formatSteps(s.Body, indent+"  ", lines)
+		if s.Kind == StepTry && len(s.Else) > 0 {
+			catch := "catch:"
+			if s.Var != "" {
+				catch = "catch " + s.Var + ":"
+			}
+			*lines = append(*lines, indent+catch)
+			formatSteps(s.Else, indent+"  ", lines)
+			continue
+		}
+		for els := s.Else; len(els) > 0; {
+			if len(els) == 1 && els[0].Kind == StepIf {
+				*lines = append(*lines, indent+"else "+els[0].String())
+				formatSteps(els[0].Body, indent+"  ", lines)
+				els = els[0].Else
+				continue
+			}
+			*lines = append(*lines, indent+"else:")
+			formatSteps(els, indent+"  ", lines)
+			break
+		}
+	}
+}
+
+// WalkSteps calls fn for every step, including those nested in blocks,
+// in source order
+func WalkSteps(steps []*Step, fn func(*Step)) {
+	for _, s := range steps {
+		fn(s)
+		WalkSteps(s.Body, fn)
+		WalkSteps(s.Else, fn)
+	}
+}
+
+// reservedStepNames are workflow variables that a step or loop variable
+// cannot rebind
+var reservedStepNames = map[string]bool{"input": true, "result": true, "steps": true}
+
+// Expr is any expression node
+type Expr interface {
+	Pos() Pos
+	String() string
+}
+
+// Ident is a bare name reference
+type Ident struct {
+	At   Pos
+	Name string
+}
+
+// StringLit is a quoted string
+type StringLit struct {
+	At    Pos
+	Value string
+}
+
+// NumberLit is a numeric literal; Raw keeps the source spelling
+type NumberLit struct {
+	At    Pos
+	Raw   string
+	Value float64
+}
+
+// BoolLit is `true` or `false`
+type BoolLit struct {
+	At    Pos
+	Value bool
+}
+
+// NullLit is `null`
+type NullLit struct {
+	At Pos
+}
+
+// ArrayLit is `[a, b, ...]`
+type ArrayLit struct {
+	At    Pos
+	Elems []Expr
+}
+
+// ObjectLit is `{ key: value, ... }`
+type ObjectLit struct {
+	At     Pos
+	Fields []*Field
+}
+
+// Field is one `key: value` entry of an object literal
+type Field struct {
+	At    Pos
+	Key   string
+	Value Expr
+}
+
+// MemberExpr is `x.name`. A nil X denotes a selector relative to the
+// current value, as in `.main.temp`.
+type MemberExpr struct {
+	At   Pos
+	X    Expr
+	Name string
+}
+
+// Dot is a bare `.`, the current value itself, as in `map(words, . | upper)`
+type Dot struct {
+	At Pos
+}
+
+// IndexExpr is `x[index]`; a nil X is relative to the current value
+type IndexExpr struct {
+	At    Pos
+	X     Expr
+	Index Expr
+}
+
+// CallExpr is `fn(args...)`
+type CallExpr struct {
+	At   Pos
+	Fun  Expr
+	Args []*Arg
+}
+
+// Arg is a positional or `name=value` call argument
+type Arg struct {
+	At    Pos
+	Name  string
+	Value Expr
+}
+
+// UnaryExpr is `!x` or `-x`
+type UnaryExpr struct {
+	At Pos
+	Op string
+	X  Expr
+}
+
+// BinaryExpr is an arithmetic, comparison or logical operation `x op y`
+type BinaryExpr struct {
+	At Pos
+	Op string
+	X  Expr
+	Y  Expr
+}
+
+// PipeExpr applies a filter to a value: `x | name` or `x | name(args...)`,
+// which is equivalent to calling name(x, args...)
+type PipeExpr struct {
+	At     Pos
+	X      Expr
+	Filter string
+	Args   []*Arg
+}
+
+// binaryPrecedence orders binary operators from loosest to tightest binding
+var binaryPrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+func (e *Ident) Pos() Pos      { return e.At }
+func (e *StringLit) Pos() Pos  { return e.At }
+func (e *NumberLit) Pos() Pos  { return e.At }
+func (e *BoolLit) Pos() Pos    { return e.At }
+func (e *NullLit) Pos() Pos    { return e.At }
+func (e *ArrayLit) Pos() Pos   { return e.At }
+func (e *ObjectLit) Pos() Pos  { return e.At }
+func (e *MemberExpr) Pos() Pos { return e.At }
+func (e *Dot) Pos() Pos        { return e.At }
+func (e *IndexExpr) Pos() Pos  { return e.At }
+func (e *CallExpr) Pos() Pos   { return e.At }
+func (e *UnaryExpr) Pos() Pos  { return e.At }
+func (e *BinaryExpr) Pos() Pos { return e.At }
+func (e *PipeExpr) Pos() Pos   { return e.At }
+
+func (e *Ident) String() string     { return e.Name }
+func (e *StringLit) String() string { return quote(e.Value) }
+func (e *NumberLit) String() string { return e.Raw }
+func (e *NullLit) String() string   { return "null" }
+func (e *Dot) String() string       { return "." }
+
+func (e *BoolLit) String() string {
+	return strconv.FormatBool(e.Value)
+}
+
+func (e *ArrayLit) String() string {
+	parts := make([]string, len(e.Elems))
+	for i, el := range e.Elems {
+		parts[i] = el.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (e *ObjectLit) String() string {
+	if len(e.Fields) == 0 {
+		return "{}"
+	}
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		key := f.Key
+		if !isIdent(key) {
+			key = quote(key)
+		}
+		parts[i] = key + ": " + f.Value.String()
+	}
+	return "{ " + strings.Join(parts, ", ") + " }"
+}
+
+func (e *MemberExpr) String() string {
+	if e.X == nil {
+		return "." + e.Name
+	}
+	return operandX(e.X) + "." + e.Name
+}
+
+func (e *IndexExpr) String() string {
+	if e.X == nil {
+		return ".[" + e.Index.String() + "]"
+	}
+	return operandX(e.X) + "[" + e.Index.String() + "]"
+}
+
+func (e *CallExpr) String() string {
+	return operandX(e.Fun) + "(" + argsString(e.Args) + ")"
+}
+
+func (e *UnaryExpr) String() string {
+	x := e.X.String()
+	switch e.X.(type) {
+	case *BinaryExpr, *PipeExpr:
+		x = "(" + x + ")"
+	}
+	return e.Op + x
+}
+
+func (e *BinaryExpr) String() string {
+	prec := binaryPrecedence[e.Op]
+	return operand(e.X, prec, false) + " " + e.Op + " " + operand(e.Y, prec, true)
+}
+
+func (e *PipeExpr) String() string {
+	s := e.X.String() + " | " + e.Filter
+	if len(e.Args) > 0 {
+		s += "(" + argsString(e.Args) + ")"
+	}
+	return s
+}
+
+// operand renders a binary operand, parenthesising it when it binds more
+// loosely than its parent. Operators are left-associative, so a right
+// operand of equal precedence needs parentheses too.
+func operand(x Expr, parent int, right bool) string {
+	var prec int
+	switch x := x.(type) {
+	case *BinaryExpr:
+		prec = binaryPrecedence[x.Op]
+	case *PipeExpr:
+		prec = 0
+	default:
+		return x.String()
+	}
+	if prec < parent || (right && prec == parent) {
+		return "(" + x.String() + ")"
+	}
+	return x.String()
+}
+
+// operandX renders the receiver of a member, index or call expression
+func operandX(x Expr) string {
+	switch x.(type) {
+	case *UnaryExpr, *BinaryExpr, *PipeExpr:
+		return "(" + x.String() + ")"
+	}
+	return x.String()
+}
+
+func argsString(args []*Arg) string {
+	parts := make([]string, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			parts[i] = a.Name + "=" + a.Value.String()
+		} else {
+			parts[i] = a.Value.String()
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// quote renders s as a double-quoted literal using only the escapes the
+// lexer understands, so that String() output always parses back.
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return s != ""
+}
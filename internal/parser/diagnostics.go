@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pos identifies a location in an ITML source file
+type Pos struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+}
+
+// String formats the position as file:line:col, omitting unknown parts
+func (p Pos) String() string {
+	switch {
+	case p.Line == 0:
+		return p.File
+	case p.File == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	default:
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Col)
+	}
+}
+
+// Diagnostic is a parse error attached to a source position
+type Diagnostic struct {
+	Pos Pos
+	Msg string
+}
+
+func (d *Diagnostic) Error() string {
+	if pos := d.Pos.String(); pos != "" {
+		return fmt.Sprintf("%s: %s", pos, d.Msg)
+	}
+	return d.Msg
+}
+
+// Diagnostics collects every error found while parsing a file
+type Diagnostics []*Diagnostic
+
+func (ds *Diagnostics) add(pos Pos, format string, args ...interface{}) {
+	*ds = append(*ds, &Diagnostic{Pos: pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+// addErr appends err, flattening nested diagnostics
+func (ds *Diagnostics) addErr(err error) {
+	switch e := err.(type) {
+	case nil:
+	case Diagnostics:
+		*ds = append(*ds, e...)
+	case *Diagnostic:
+		*ds = append(*ds, e)
+	default:
+		*ds = append(*ds, &Diagnostic{Msg: err.Error()})
+	}
+}
+
+// sortByPos orders diagnostics by their position in the file
+func (ds Diagnostics) sortByPos() {
+	sort.SliceStable(ds, func(i, j int) bool {
+		a, b := ds[i].Pos, ds[j].Pos
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+}
+
+// Err returns nil when there are no diagnostics
+func (ds Diagnostics) Err() error {
+	if len(ds) == 0 {
+		return nil
+	}
+	return ds
+}
+
+func (ds Diagnostics) Error() string {
+	if len(ds) == 1 {
+		return ds[0].Error()
+	}
+	lines := make([]string, len(ds))
+	for i, d := range ds {
+		lines[i] = "  " + d.Error()
+	}
+	return fmt.Sprintf("%d errors:\n%s", len(ds), strings.Join(lines, "\n"))
+}
+
+// Unwrap exposes the individual diagnostics to errors.Is and errors.As
+func (ds Diagnostics) Unwrap() []error {
+	errs := make([]error, len(ds))
+	for i, d := range ds {
+		errs[i] = d
+	}
+	return errs
+}
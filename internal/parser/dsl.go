@@ -0,0 +1,1193 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dslParser is a recursive-descent parser over the token stream produced by
+// lex. Errors are collected rather than returned so that one pass reports
+// every problem in the file.
+type dslParser struct {
+	toks  []token
+	i     int
+	diags Diagnostics
+}
+
+// ParseFile parses ITML DSL source into an AST. On failure the error is a
+// Diagnostics value listing every problem with its position.
+func ParseFile(filename string, src []byte) (*File, error) {
+	f, diags := parseDSL(filename, src)
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	return f, nil
+}
+
+// parseDSL parses src and returns the (possibly partial) AST with all diagnostics
+func parseDSL(filename string, src []byte) (*File, Diagnostics) {
+	toks, diags := lex(filename, src)
+	p := &dslParser{toks: toks, diags: diags}
+	f := p.parseFile()
+	return f, p.diags
+}
+
+// ParseExpr parses a single standalone expression
+func ParseExpr(filename, src string) (Expr, error) {
+	toks, diags := lex(filename, []byte(src))
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	p := &dslParser{toks: toks}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipNewlines()
+	if !p.at(tokEOF) {
+		return nil, p.unexpected("after expression")
+	}
+	return expr, nil
+}
+
+// report records err unless its line already has a diagnostic, which
+// keeps a single mistake from cascading into several messages.
+func (p *dslParser) report(err error) {
+	d, ok := err.(*Diagnostic)
+	if !ok {
+		p.diags.addErr(err)
+		return
+	}
+	for _, prev := range p.diags {
+		if prev.Pos.File == d.Pos.File && prev.Pos.Line == d.Pos.Line {
+			return
+		}
+	}
+	p.diags = append(p.diags, d)
+}
+
+// sync skips to the start of the next line after an error
+func (p *dslParser) sync() {
+	for !p.at(tokNewline) && !p.at(tokEOF) {
+		p.advance()
+	}
+	p.skipNewlines()
+}
+
+func (p *dslParser) tok() token {
+	return p.toks[p.i]
+}
+
+func (p *dslParser) at(kind tokenKind) bool {
+	return p.toks[p.i].kind == kind
+}
+
+func (p *dslParser) advance() token {
+	t := p.toks[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+func (p *dslParser) peekKind(n int) tokenKind {
+	if p.i+n >= len(p.toks) {
+		return tokEOF
+	}
+	return p.toks[p.i+n].kind
+}
+
+func (p *dslParser) skipNewlines() {
+	for p.at(tokNewline) {
+		p.advance()
+	}
+}
+
+func (p *dslParser) errorf(pos Pos, format string, args ...interface{}) error {
+	return &Diagnostic{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *dslParser) unexpected(context string) error {
+	t := p.tok()
+	return p.errorf(t.pos, "unexpected %s %s", t.describe(), context)
+}
+
+func (p *dslParser) expect(kind tokenKind, context string) (token, error) {
+	if !p.at(kind) {
+		t := p.tok()
+		return t, p.errorf(t.pos, "expected %s %s, found %s", kind, context, t.describe())
+	}
+	return p.advance(), nil
+}
+
+func (p *dslParser) expectEOL(context string) error {
+	if p.at(tokEOF) {
+		return nil
+	}
+	_, err := p.expect(tokNewline, context)
+	return err
+}
+
+func (p *dslParser) parseFile() *File {
+	f := &File{}
+
+	for {
+		p.skipNewlines()
+		if p.at(tokEOF) {
+			break
+		}
+		if err := p.parseTopLevel(f); err != nil {
+			p.report(err)
+			p.sync()
+		}
+	}
+
+	if f.Intent == nil {
+		p.report(p.errorf(p.tok().pos, "missing intent declaration (expected `intent \"Name\"`)"))
+	}
+	return f
+}
+
+func (p *dslParser) parseTopLevel(f *File) error {
+	t := p.tok()
+	if t.kind != tokIdent {
+		return p.errorf(t.pos, "expected intent declaration, section or key, found %s", t.describe())
+	}
+
+	if t.text == "intent" && p.peekKind(1) == tokString {
+		p.advance()
+		name := p.advance()
+		if f.Intent != nil {
+			return p.errorf(t.pos, "duplicate intent declaration (first declared at %s)", f.Intent.At)
+		}
+		f.Intent = &IntentDecl{At: t.pos, Name: name.text}
+		return p.expectEOL("after intent declaration")
+	}
+
+	p.advance()
+	if _, err := p.expect(tokColon, fmt.Sprintf("after %q", t.text)); err != nil {
+		return err
+	}
+
+	if !p.at(tokNewline) && !p.at(tokEOF) {
+		value, err := p.parseExpr()
+		if err != nil {
+			return err
+		}
+		f.Meta = append(f.Meta, &MetaEntry{At: t.pos, Key: t.text, Value: value})
+		return p.expectEOL(fmt.Sprintf("after value of %q", t.text))
+	}
+
+	p.parseSection(f, t)
+	return nil
+}
+
+func (p *dslParser) parseSection(f *File, header token) {
+	section := &Section{At: header.pos, Name: header.text}
+
+	var slot **Section
+	switch header.text {
+	case "inputs":
+		slot = &f.Inputs
+	case "outputs":
+		slot = &f.Outputs
+	case "workflow":
+		slot = &f.Workflow
+	default:
+		p.report(p.errorf(header.pos, "unknown section %q (expected inputs, outputs or workflow)", header.text))
+	}
+	if slot != nil {
+		if *slot != nil {
+			p.report(p.errorf(header.pos, "duplicate %s section (first declared at %s)", header.text, (*slot).At))
+		} else {
+			*slot = section
+		}
+	}
+
+	// The body of an unknown or duplicate section is still parsed so
+	// that errors inside it are reported too.
+	workflow := header.text == "workflow"
+	known := slot != nil
+	var lines []*stepLine
+	for {
+		p.skipNewlines()
+
+		var err error
+		switch {
+		case p.at(tokArrow) && (workflow || !known):
+			var line *stepLine
+			if line, err = p.parseLine(p.advance().pos); err == nil {
+				lines = append(lines, line)
+			}
+		case (p.isKeyword("else") || p.isKeyword("catch")) && workflow:
+			var line *stepLine
+			if line, err = p.parseLine(p.tok().pos); err == nil {
+				lines = append(lines, line)
+			}
+		case p.at(tokMinus) && (!workflow || !known):
+			var decl *ParamDecl
+			if decl, err = p.parseParamDecl(); err == nil {
+				section.Params = append(section.Params, decl)
+			}
+		case p.at(tokArrow), p.at(tokMinus):
+			err = p.unexpected(fmt.Sprintf("in %s section", header.text))
+		default:
+			section.Steps = nestSteps(lines, &p.diags)
+			return
+		}
+
+		if err != nil {
+			p.report(err)
+			p.sync()
+		}
+	}
+}
+
+// parseParamDecl parses `- name (type) attr attr=value ...`
+func (p *dslParser) parseParamDecl() (*ParamDecl, error) {
+	dash := p.advance()
+
+	name, err := p.expect(tokIdent, "for declaration name")
+	if err != nil {
+		return nil, err
+	}
+	decl := &ParamDecl{At: dash.pos, Name: name.text}
+
+	if _, err := p.expect(tokLParen, fmt.Sprintf("before type of %q", name.text)); err != nil {
+		return nil, err
+	}
+	typ, err := p.expect(tokIdent, fmt.Sprintf("for type of %q", name.text))
+	if err != nil {
+		return nil, err
+	}
+	decl.Type, decl.TypeAt = typ.text, typ.pos
+	if _, err := p.expect(tokRParen, "after type"); err != nil {
+		return nil, err
+	}
+
+	for !p.at(tokNewline) && !p.at(tokEOF) {
+		key, err := p.expect(tokIdent, fmt.Sprintf("for attribute of %q", name.text))
+		if err != nil {
+			return nil, err
+		}
+		attr := &Attr{At: key.pos, Key: key.text}
+		if p.at(tokAssign) {
+			p.advance()
+			if attr.Value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		decl.Attrs = append(decl.Attrs, attr)
+	}
+	return decl, p.expectEOL("after declaration")
+}
+
+// isKeyword reports whether the current token is the control-flow keyword
+// word. A keyword followed by '=' is an ordinary step name.
+func (p *dslParser) isKeyword(word string) bool {
+	return p.at(tokIdent) && p.tok().text == word && p.peekKind(1) != tokAssign
+}
+
+// stepLine is one parsed workflow line, before nestSteps groups lines
+// into blocks by indentation. Lines that continue a block have clause
+// set: `else:` and `catch name:` lines have a nil step, and an
+// `else if cond:` line has an if step.
+type stepLine struct {
+	at     Pos
+	step   *Step
+	clause string // "else" or "catch"
+	name   string // the error variable of `catch name:`
+}
+
+// parseLine parses a workflow line whose arrow, if any, has been consumed
+func (p *dslParser) parseLine(at Pos) (*stepLine, error) {
+	line := &stepLine{at: at}
+	switch {
+	case p.isKeyword("else"):
+		p.advance()
+		line.clause = "else"
+		if !p.isKeyword("if") {
+			return line, p.expectBlock("else")
+		}
+	case p.isKeyword("catch"):
+		p.advance()
+		line.clause = "catch"
+		if p.at(tokIdent) {
+			line.name = p.advance().text
+		}
+		return line, p.expectBlock("catch")
+	}
+	step, err := p.parseStepBody(at)
+	if err != nil {
+		return nil, err
+	}
+	line.step = step
+	return line, nil
+}
+
+func (p *dslParser) parseStepBody(at Pos) (*Step, error) {
+	step := &Step{At: at}
+	var err error
+	switch {
+	case p.isKeyword("if"):
+		p.advance()
+		step.Kind = StepIf
+		if step.Expr, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		return step, p.expectBlock("if condition")
+	case p.isKeyword("for"):
+		p.advance()
+		step.Kind = StepFor
+		name, err := p.expect(tokIdent, "for loop variable")
+		if err != nil {
+			return nil, err
+		}
+		step.Var = name.text
+		if !p.isKeyword("in") {
+			return nil, p.unexpected("after loop variable (expected 'in')")
+		}
+		p.advance()
+		if step.Expr, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		return step, p.expectBlock("for loop")
+	case p.isKeyword("try"):
+		p.advance()
+		step.Kind = StepTry
+		return step, p.expectBlock("try")
+	}
+
+	if p.at(tokIdent) && p.peekKind(1) == tokAssign {
+		step.Name = p.advance().text
+		p.advance()
+	}
+	if p.isKeyword("parallel") {
+		p.advance()
+		step.Kind = StepParallel
+		if step.Attrs, err = p.parseStepAttrs(); err != nil {
+			return nil, err
+		}
+		step.Options = parallelOptions(step.Attrs, &p.diags)
+		return step, p.expectBlock("parallel")
+	}
+	if step.Expr, err = p.parseExpr(); err != nil {
+		return nil, err
+	}
+	if step.Attrs, err = p.parseStepAttrs(); err != nil {
+		return nil, err
+	}
+	step.Options = stepOptions(step.Attrs, &p.diags)
+	return step, p.expectEOL("after workflow step")
+}
+
+// parseStepAttrs parses the `key` and `key=value` attributes ending a step
+func (p *dslParser) parseStepAttrs() ([]*Attr, error) {
+	var attrs []*Attr
+	for p.at(tokIdent) {
+		attr := &Attr{At: p.tok().pos, Key: p.advance().text}
+		if p.at(tokAssign) {
+			p.advance()
+			var err error
+			if attr.Value, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	return attrs, nil
+}
+
+// parallelOptions converts the attributes of a parallel step. Branches
+// fail fast unless fail_fast=false.
+func parallelOptions(attrs []*Attr, diags *Diagnostics) StepOptions {
+	opts := StepOptions{OnError: OnErrorFail, FailFast: true}
+	seen := make(map[string]Pos)
+	for _, attr := range attrs {
+		if first, dup := seen[attr.Key]; dup {
+			diags.add(attr.At, "duplicate parallel attribute %q (first used at %s)", attr.Key, first)
+			continue
+		}
+		seen[attr.Key] = attr.At
+
+		switch attr.Key {
+		case "limit", "fail_fast":
+		default:
+			diags.add(attr.At, "unknown parallel attribute %q (expected limit or fail_fast)", attr.Key)
+			continue
+		}
+		if attr.Value == nil {
+			diags.add(attr.At, "parallel attribute %q requires a value", attr.Key)
+			continue
+		}
+
+		switch attr.Key {
+		case "limit":
+			num, ok := attr.Value.(*NumberLit)
+			if !ok || num.Value < 1 || num.Value != float64(int(num.Value)) {
+				diags.add(attr.Value.Pos(), "\"limit\" must be a whole number of branches, found %s", attr.Value)
+				continue
+			}
+			opts.Limit = int(num.Value)
+		case "fail_fast":
+			b, ok := attr.Value.(*BoolLit)
+			if !ok {
+				diags.add(attr.Value.Pos(), "\"fail_fast\" must be true or false, found %s", attr.Value)
+				continue
+			}
+			opts.FailFast = b.Value
+		}
+	}
+	return opts
+}
+
+// stepOptions converts the attributes of a plain step
+func stepOptions(attrs []*Attr, diags *Diagnostics) StepOptions {
+	opts := StepOptions{Backoff: DefaultRetryBackoff, OnError: OnErrorFail}
+	seen := make(map[string]Pos)
+	for _, attr := range attrs {
+		if first, dup := seen[attr.Key]; dup {
+			diags.add(attr.At, "duplicate step attribute %q (first used at %s)", attr.Key, first)
+			continue
+		}
+		seen[attr.Key] = attr.At
+
+		switch attr.Key {
+		case "retry", "backoff", "timeout", "on_error":
+		default:
+			diags.add(attr.At, "unknown step attribute %q (expected retry, backoff, timeout or on_error)", attr.Key)
+			continue
+		}
+		if attr.Value == nil {
+			diags.add(attr.At, "step attribute %q requires a value", attr.Key)
+			continue
+		}
+
+		switch attr.Key {
+		case "retry":
+			num, ok := attr.Value.(*NumberLit)
+			if !ok || num.Value < 0 || num.Value != float64(int(num.Value)) {
+				diags.add(attr.Value.Pos(), "\"retry\" must be a whole number of retries, found %s", attr.Value)
+				continue
+			}
+			opts.Retry = int(num.Value)
+		case "backoff", "timeout":
+			value, err := stringValue(attr.Value, attr.Key)
+			if err != nil {
+				diags.addErr(err)
+				continue
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 || (d == 0 && attr.Key == "timeout") {
+				diags.add(attr.Value.Pos(), "%q must be a duration such as \"10s\", found %s", attr.Key, attr.Value)
+				continue
+			}
+			if attr.Key == "backoff" {
+				opts.Backoff = d
+			} else {
+				opts.Timeout = d
+			}
+		case "on_error":
+			switch v := attr.Value.(type) {
+			case *Ident:
+				if v.Name == OnErrorContinue || v.Name == OnErrorFail {
+					opts.OnError = v.Name
+					continue
+				}
+			case *CallExpr:
+				if fun, ok := v.Fun.(*Ident); ok && fun.Name == OnErrorFallback && len(v.Args) == 1 && v.Args[0].Name == "" {
+					opts.OnError, opts.Fallback = OnErrorFallback, v.Args[0].Value
+					continue
+				}
+			}
+			diags.add(attr.Value.Pos(), "\"on_error\" must be continue, fail or fallback(value), found %s", attr.Value)
+		}
+	}
+	return opts
+}
+
+// expectBlock expects the ':' that ends a block header
+func (p *dslParser) expectBlock(context string) error {
+	if _, err := p.expect(tokColon, "after "+context); err != nil {
+		return err
+	}
+	return p.expectEOL("after ':'")
+}
+
+// ParseWorkflow parses a workflow script as stored in Intent.Script: one
+// step per line, each optionally prefixed with an arrow. Expressions may
+// span lines inside brackets, and the steps of an `if`, `else` or `for`
+// block are indented below it.
+func ParseWorkflow(filename, src string) ([]*Step, error) {
+	toks, diags := lex(filename, []byte(src))
+	p := &dslParser{toks: toks, diags: diags}
+
+	var lines []*stepLine
+	for p.skipNewlines(); !p.at(tokEOF); p.skipNewlines() {
+		at := p.tok().pos
+		if p.at(tokArrow) {
+			p.advance()
+		}
+		line, err := p.parseLine(at)
+		if err != nil {
+			p.report(err)
+			p.sync()
+			continue
+		}
+		lines = append(lines, line)
+	}
+	steps := nestSteps(lines, &p.diags)
+	checkSteps(steps, &p.diags)
+
+	if len(p.diags) > 0 {
+		p.diags.sortByPos()
+		return nil, p.diags
+	}
+	return steps, nil
+}
+
+// nestSteps builds the step tree from lines: the lines indented further
+// than a block header form its body, and `else` lines attach to the `if`
+// at their own indentation.
+func nestSteps(lines []*stepLine, diags *Diagnostics) []*Step {
+	n := &nester{lines: lines, diags: diags}
+	return n.block(0)
+}
+
+type nester struct {
+	lines []*stepLine
+	i     int
+	diags *Diagnostics
+}
+
+// block consumes the lines indented further than parentCol
+func (n *nester) block(parentCol int) []*Step {
+	var steps []*Step
+	var open *Step // the if or try an else or catch at this level attaches to
+	col := 0
+	for n.i < len(n.lines) {
+		line := n.lines[n.i]
+		if line.at.Col <= parentCol {
+			break
+		}
+		n.i++
+		if col == 0 {
+			col = line.at.Col
+		} else if line.at.Col != col {
+			n.diags.add(line.at, "inconsistent indentation (expected column %d)", col)
+		}
+
+		step := line.step
+		if step != nil && step.Kind != StepExpr {
+			step.Body = n.block(line.at.Col)
+			if len(step.Body) == 0 {
+				n.diags.add(line.at, "expected an indented block after %q", step.String())
+			}
+		}
+
+		if line.clause == "" {
+			steps = append(steps, step)
+			n.close(open)
+			open = nil
+			if step.Kind == StepIf || step.Kind == StepTry {
+				open = step
+			}
+			continue
+		}
+
+		want, keyword := StepIf, "if"
+		if line.clause == "catch" {
+			want, keyword = StepTry, "try"
+		}
+		if open == nil || open.Kind != want {
+			n.diags.add(line.at, "%s without a matching %s", line.clause, keyword)
+			n.block(line.at.Col)
+			continue
+		}
+		if step != nil {
+			open.Else = []*Step{step}
+			open = step
+			continue
+		}
+		open.Var = line.name
+		open.Else = n.block(line.at.Col)
+		if len(open.Else) == 0 {
+			n.diags.add(line.at, "expected an indented block after %q", line.clause+":")
+		}
+		open = nil
+	}
+	n.close(open)
+	return steps
+}
+
+// close reports a try block that ended without a catch
+func (n *nester) close(open *Step) {
+	if open != nil && open.Kind == StepTry {
+		n.diags.add(open.At, "try without a matching catch")
+	}
+}
+
+// checkSteps reports step names, loop variables and caught errors that
+// are reserved or used twice
+func checkSteps(steps []*Step, diags *Diagnostics) {
+	seen := make(map[string]*Step)
+	WalkSteps(steps, func(step *Step) {
+		name := step.Name
+		if step.Kind == StepFor || step.Kind == StepTry {
+			name = step.Var
+		}
+		if name == "" {
+			return
+		}
+		if reservedStepNames[name] {
+			diags.add(step.At, "step name %q is reserved", name)
+		} else if prev, ok := seen[name]; ok {
+			diags.add(step.At, "duplicate step name %q (first used at %s)", name, prev.At)
+		} else {
+			seen[name] = step
+		}
+	})
+}
+
+// parseExpr parses a full expression. Filters bind loosest, so
+// `a + b | upper` applies upper to the sum.
+func (p *dslParser) parseExpr() (Expr, error) {
+	x, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	for p.at(tokPipe) {
+		bar := p.advance()
+		name, err := p.expect(tokIdent, "for filter name after '|'")
+		if err != nil {
+			return nil, err
+		}
+		pipe := &PipeExpr{At: bar.pos, X: x, Filter: name.text}
+		if p.at(tokLParen) {
+			p.advance()
+			if pipe.Args, err = p.parseArgs(); err != nil {
+				return nil, err
+			}
+		}
+		x = pipe
+	}
+	return x, nil
+}
+
+// binaryOps maps operator tokens to their spelling in BinaryExpr.Op
+var binaryOps = map[tokenKind]string{
+	tokOr:      "||",
+	tokAnd:     "&&",
+	tokEq:      "==",
+	tokNeq:     "!=",
+	tokLt:      "<",
+	tokLe:      "<=",
+	tokGt:      ">",
+	tokGe:      ">=",
+	tokPlus:    "+",
+	tokMinus:   "-",
+	tokStar:    "*",
+	tokSlash:   "/",
+	tokPercent: "%",
+}
+
+// parseBinary parses binary operators by precedence climbing
+func (p *dslParser) parseBinary(minPrec int) (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := binaryOps[p.tok().kind]
+		if !ok || binaryPrecedence[op] < minPrec {
+			return x, nil
+		}
+		t := p.advance()
+		y, err := p.parseBinary(binaryPrecedence[op] + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &BinaryExpr{At: t.pos, Op: op, X: x, Y: y}
+	}
+}
+
+func (p *dslParser) parseUnary() (Expr, error) {
+	t := p.tok()
+	// A minus directly before a number stays part of the literal
+	if t.kind == tokNot || (t.kind == tokMinus && p.peekKind(1) != tokNumber) {
+		p.advance()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{At: t.pos, Op: t.text, X: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *dslParser) parsePostfix() (Expr, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.at(tokDot):
+			p.advance()
+			name, err := p.expect(tokIdent, "after '.'")
+			if err != nil {
+				return nil, err
+			}
+			x = &MemberExpr{At: name.pos, X: x, Name: name.text}
+		case p.at(tokLBracket):
+			lb := p.advance()
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokRBracket, "to close index"); err != nil {
+				return nil, err
+			}
+			x = &IndexExpr{At: lb.pos, X: x, Index: index}
+		case p.at(tokLParen):
+			lp := p.advance()
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			x = &CallExpr{At: lp.pos, Fun: x, Args: args}
+		default:
+			return x, nil
+		}
+	}
+}
+
+func (p *dslParser) parseArgs() ([]*Arg, error) {
+	var args []*Arg
+	for !p.at(tokRParen) {
+		t := p.tok()
+		arg := &Arg{At: t.pos}
+		if t.kind == tokIdent && p.peekKind(1) == tokAssign {
+			p.advance()
+			p.advance()
+			arg.Name = t.text
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arg.Value = value
+		args = append(args, arg)
+
+		if !p.at(tokComma) {
+			break
+		}
+		p.advance()
+	}
+	if _, err := p.expect(tokRParen, "to close argument list"); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *dslParser) parsePrimary() (Expr, error) {
+	t := p.tok()
+	switch t.kind {
+	case tokIdent:
+		p.advance()
+		switch t.text {
+		case "true", "false":
+			return &BoolLit{At: t.pos, Value: t.text == "true"}, nil
+		case "null":
+			return &NullLit{At: t.pos}, nil
+		}
+		return &Ident{At: t.pos, Name: t.text}, nil
+	case tokString:
+		p.advance()
+		return &StringLit{At: t.pos, Value: t.text}, nil
+	case tokNumber:
+		p.advance()
+		return p.number(t, "")
+	case tokMinus:
+		p.advance()
+		num, err := p.expect(tokNumber, "after '-'")
+		if err != nil {
+			return nil, err
+		}
+		return p.number(num, "-")
+	case tokLParen:
+		p.advance()
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		_, err = p.expect(tokRParen, "to close parenthesis")
+		return x, err
+	case tokLBracket:
+		return p.parseArray()
+	case tokLBrace:
+		return p.parseObject()
+	case tokDot:
+		// jq-style selector relative to the current value: .field, .[n] or .
+		p.advance()
+		if !p.at(tokLBracket) && !p.at(tokIdent) {
+			return &Dot{At: t.pos}, nil
+		}
+		if p.at(tokLBracket) {
+			p.advance()
+			index, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(tokRBracket, "to close index"); err != nil {
+				return nil, err
+			}
+			return &IndexExpr{At: t.pos, Index: index}, nil
+		}
+		name, err := p.expect(tokIdent, "after '.'")
+		if err != nil {
+			return nil, err
+		}
+		return &MemberExpr{At: t.pos, Name: name.text}, nil
+	default:
+		return nil, p.unexpected("in expression")
+	}
+}
+
+func (p *dslParser) number(t token, sign string) (Expr, error) {
+	raw := sign + t.text
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, p.errorf(t.pos, "invalid number %q", raw)
+	}
+	return &NumberLit{At: t.pos, Raw: raw, Value: v}, nil
+}
+
+func (p *dslParser) parseArray() (Expr, error) {
+	lb := p.advance()
+	arr := &ArrayLit{At: lb.pos}
+	for !p.at(tokRBracket) {
+		el, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arr.Elems = append(arr.Elems, el)
+		if !p.at(tokComma) {
+			break
+		}
+		p.advance()
+	}
+	_, err := p.expect(tokRBracket, "to close array")
+	return arr, err
+}
+
+func (p *dslParser) parseObject() (Expr, error) {
+	lb := p.advance()
+	obj := &ObjectLit{At: lb.pos}
+	for !p.at(tokRBrace) {
+		key := p.tok()
+		if key.kind != tokIdent && key.kind != tokString {
+			return nil, p.errorf(key.pos, "expected object key, found %s", key.describe())
+		}
+		p.advance()
+		if _, err := p.expect(tokColon, fmt.Sprintf("after object key %q", key.text)); err != nil {
+			return nil, err
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		obj.Fields = append(obj.Fields, &Field{At: key.pos, Key: key.text, Value: value})
+		if !p.at(tokComma) {
+			break
+		}
+		p.advance()
+	}
+	_, err := p.expect(tokRBrace, "to close object")
+	return obj, err
+}
+
+// buildIntent converts a parsed DSL file into an Intent, reporting every
+// semantic problem (unknown keys, bad types, duplicates) with its position
+func buildIntent(f *File) (*Intent, error) {
+	var diags Diagnostics
+
+	intent := &Intent{
+		Name:       f.Intent.Name,
+		Version:    "1.0.0",
+		Author:     "Unknown",
+		License:    "MIT",
+		Tags:       []string{},
+		Parameters: []Parameter{},
+		Outputs:    []Output{},
+		Examples:   []Example{},
+		Config:     make(map[string]interface{}),
+	}
+
+	applyMetadata(intent, f.Meta, &diags)
+	if intent.Description == "" {
+		intent.Description = intent.Name
+	}
+
+	if f.Inputs != nil {
+		checkDecls(f.Inputs, &diags)
+		for _, decl := range f.Inputs.Params {
+			intent.Parameters = append(intent.Parameters, buildParameter(decl, &diags))
+		}
+	}
+
+	if f.Outputs != nil {
+		checkDecls(f.Outputs, &diags)
+		for _, decl := range f.Outputs.Params {
+			intent.Outputs = append(intent.Outputs, buildOutput(decl, &diags))
+		}
+	}
+
+	// Convert workflow steps to script, one `→ step` per line
+	if f.Workflow != nil && len(f.Workflow.Steps) > 0 {
+		checkSteps(f.Workflow.Steps, &diags)
+		intent.Script = FormatWorkflow(f.Workflow.Steps)
+	}
+
+	if len(diags) > 0 {
+		return nil, diags
+	}
+	return intent, nil
+}
+
+// checkDecls reports unknown types and duplicate names within a section
+func checkDecls(section *Section, diags *Diagnostics) {
+	seen := make(map[string]Pos)
+	for _, decl := range section.Params {
+		if first, dup := seen[decl.Name]; dup {
+			diags.add(decl.At, "duplicate %s %q (first declared at %s)", strings.TrimSuffix(section.Name, "s"), decl.Name, first)
+		} else {
+			seen[decl.Name] = decl.At
+		}
+		if !isValidType(decl.Type) {
+			diags.add(decl.TypeAt, "invalid type %q for %q", decl.Type, decl.Name)
+		} else if decl.Type == "secret" && section.Name == "outputs" {
+			diags.add(decl.TypeAt, "%s, found output %q", errSecretOutput, decl.Name)
+		}
+	}
+}
+
+// applyMetadata copies top-level `key: value` entries onto the intent
+func applyMetadata(intent *Intent, meta []*MetaEntry, diags *Diagnostics) {
+	seen := make(map[string]Pos)
+	for _, m := range meta {
+		if first, dup := seen[m.Key]; dup {
+			diags.add(m.At, "duplicate %q (first declared at %s)", m.Key, first)
+			continue
+		}
+		seen[m.Key] = m.At
+
+		var target *string
+		switch m.Key {
+		case "description":
+			target = &intent.Description
+		case "version":
+			target = &intent.Version
+		case "author":
+			target = &intent.Author
+		case "license":
+			target = &intent.License
+		case "itmlVersion":
+			target = &intent.ItmlVersion
+		case "cache":
+			target = &intent.Cache
+		case "tags":
+			tags, err := stringList(m.Value, m.Key)
+			if err != nil {
+				diags.addErr(err)
+				continue
+			}
+			intent.Tags = tags
+			continue
+		default:
+			diags.add(m.At, "unknown key %q (expected description, version, author, license, tags, itmlVersion or cache)", m.Key)
+			continue
+		}
+
+		value, err := stringValue(m.Value, m.Key)
+		if err != nil {
+			diags.addErr(err)
+			continue
+		}
+		*target = value
+		if m.Key == "cache" {
+			if _, err := intent.CacheTTL(); err != nil {
+				diags.add(m.Value.Pos(), "%v", err)
+			}
+		}
+	}
+}
+
+// buildParameter converts an inputs entry such as
+// `- days (integer) required min=1 max=14 description="Forecast length"`
+func buildParameter(decl *ParamDecl, diags *Diagnostics) Parameter {
+	param := Parameter{Name: decl.Name, Type: decl.Type}
+	seen := make(map[string]Pos)
+
+	for _, attr := range decl.Attrs {
+		key := attr.Key
+		if key == "enum" {
+			key = "options"
+		}
+		if first, dup := seen[key]; dup {
+			diags.add(attr.At, "duplicate attribute %q for input %q (first declared at %s)", attr.Key, decl.Name, first)
+			continue
+		}
+		seen[key] = attr.At
+
+		switch key {
+		case "required":
+			param.Required = true
+			if attr.Value != nil {
+				b, ok := attr.Value.(*BoolLit)
+				if !ok {
+					diags.add(attr.Value.Pos(), "\"required\" must be true or false, found %s", attr.Value)
+					continue
+				}
+				param.Required = b.Value
+			}
+			continue
+		case "description", "pattern", "default", "min", "max", "options":
+		default:
+			diags.add(attr.At, "unknown attribute %q for input %q (expected required, default, description, min, max, pattern or options)", attr.Key, decl.Name)
+			continue
+		}
+
+		if attr.Value == nil {
+			diags.add(attr.At, "attribute %q of input %q requires a value", attr.Key, decl.Name)
+			continue
+		}
+
+		switch key {
+		case "description", "pattern":
+			value, err := stringValue(attr.Value, attr.Key)
+			if err != nil {
+				diags.addErr(err)
+				continue
+			}
+			if key == "description" {
+				param.Description = value
+				continue
+			}
+			param.Validation.Pattern = value
+			if _, err := param.Validation.PatternRegexp(); err != nil {
+				diags.add(attr.Value.Pos(), "input %q: %v", decl.Name, err)
+			}
+		case "default":
+			if decl.Type == "secret" {
+				diags.add(attr.At, "input %q: %s", decl.Name, errSecretDefault)
+				continue
+			}
+			value, err := literalValue(attr.Value)
+			if err != nil {
+				diags.addErr(err)
+				continue
+			}
+			if isValidType(decl.Type) {
+				if value, err = CoerceValue(value, decl.Type); err != nil {
+					diags.add(attr.Value.Pos(), "invalid default for input %q: %v", decl.Name, err)
+					continue
+				}
+			}
+			param.Default = value
+		case "min", "max":
+			num, ok := attr.Value.(*NumberLit)
+			if !ok {
+				diags.add(attr.Value.Pos(), "%q must be a number, found %s", attr.Key, attr.Value)
+				continue
+			}
+			v := num.Value
+			if key == "min" {
+				param.Validation.Min = &v
+			} else {
+				param.Validation.Max = &v
+			}
+		case "options":
+			options, err := stringList(attr.Value, attr.Key)
+			if err != nil {
+				diags.addErr(err)
+				continue
+			}
+			param.Validation.Options = options
+		}
+	}
+
+	if v := param.Validation; v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+		diags.add(seen["min"], "min (%g) is greater than max (%g) for input %q", *v.Min, *v.Max, decl.Name)
+	}
+	return param
+}
+
+// buildOutput converts an outputs entry like `- summary (string) format="markdown"`
+func buildOutput(decl *ParamDecl, diags *Diagnostics) Output {
+	output := Output{Name: decl.Name, Type: decl.Type}
+	seen := make(map[string]Pos)
+	for _, attr := range decl.Attrs {
+		if first, dup := seen[attr.Key]; dup {
+			diags.add(attr.At, "duplicate attribute %q for output %q (first declared at %s)", attr.Key, decl.Name, first)
+			continue
+		}
+		seen[attr.Key] = attr.At
+
+		var target *string
+		switch attr.Key {
+		case "description":
+			target = &output.Description
+		case "format":
+			target = &output.Format
+		default:
+			diags.add(attr.At, "unknown attribute %q for output %q (expected description or format)", attr.Key, decl.Name)
+			continue
+		}
+		if attr.Value == nil {
+			diags.add(attr.At, "attribute %q of output %q requires a value", attr.Key, decl.Name)
+			continue
+		}
+		value, err := stringValue(attr.Value, attr.Key)
+		if err != nil {
+			diags.addErr(err)
+			continue
+		}
+		*target = value
+	}
+	return output
+}
+
+// stringValue requires e to be a string literal (numbers are accepted verbatim)
+func stringValue(e Expr, key string) (string, error) {
+	switch v := e.(type) {
+	case *StringLit:
+		return v.Value, nil
+	case *NumberLit:
+		return v.Raw, nil
+	}
+	return "", &Diagnostic{Pos: e.Pos(), Msg: fmt.Sprintf("%q must be a string, found %s", key, e)}
+}
+
+// stringList accepts either a single string or an array of strings
+func stringList(e Expr, key string) ([]string, error) {
+	arr, ok := e.(*ArrayLit)
+	if !ok {
+		s, err := stringValue(e, key)
+		if err != nil {
+			return nil, err
+		}
+		return []string{s}, nil
+	}
+	list := make([]string, 0, len(arr.Elems))
+	for _, el := range arr.Elems {
+		s, err := stringValue(el, key)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
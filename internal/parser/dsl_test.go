@@ -0,0 +1,658 @@
+package parser
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFile_Examples(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "..", "examples", "*.itml"))
+	if err != nil {
+		t.Fatalf("Failed to list examples: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("No example intents found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", file, err)
+			}
+			if !strings.HasPrefix(strings.TrimSpace(string(content)), "{") {
+				if _, err := ParseFile(file, content); err != nil {
+					t.Errorf("ParseFile(%s) failed: %v", file, err)
+				}
+			}
+			if _, err := ParseITML(file); err != nil {
+				t.Errorf("ParseITML(%s) failed: %v", file, err)
+			}
+		})
+	}
+}
+
+func TestParseFile_AST(t *testing.T) {
+	src := `intent "Weather"
+description: "Fetch weather"
+
+inputs:
+  - city (string) required default="London"
+
+workflow:
+  → log("Fetching {city}")
+  → transform(input, {
+      temperature: .main.temp,
+      condition: .weather[0].main
+    })
+  → return(status="ok")
+`
+	f, err := ParseFile("weather.itml", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	if f.Intent.Name != "Weather" {
+		t.Errorf("Expected intent name 'Weather', got %q", f.Intent.Name)
+	}
+	if len(f.Meta) != 1 || f.Meta[0].Key != "description" {
+		t.Errorf("Expected description metadata, got %+v", f.Meta)
+	}
+
+	if f.Inputs == nil || len(f.Inputs.Params) != 1 {
+		t.Fatalf("Expected one input declaration")
+	}
+	city := f.Inputs.Params[0]
+	if city.Name != "city" || city.Type != "string" {
+		t.Errorf("Unexpected declaration: %s (%s)", city.Name, city.Type)
+	}
+	if city.At.Line != 5 || city.At.Col != 3 {
+		t.Errorf("Expected declaration at 5:3, got %s", city.At)
+	}
+	if city.Attr("required") == nil || city.Attr("default") == nil {
+		t.Errorf("Expected required and default attributes, got %+v", city.Attrs)
+	}
+
+	if f.Workflow == nil || len(f.Workflow.Steps) != 3 {
+		t.Fatalf("Expected three workflow steps")
+	}
+	transform := f.Workflow.Steps[1]
+	if transform.At.Line != 9 {
+		t.Errorf("Expected multi-line step at line 9, got %s", transform.At)
+	}
+	want := `transform(input, { temperature: .main.temp, condition: .weather[0].main })`
+	if got := transform.Expr.String(); got != want {
+		t.Errorf("Expected step %q, got %q", want, got)
+	}
+}
+
+func TestParseFile_Diagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		pos  string
+		msg  string
+	}{
+		{
+			name: "missing intent",
+			src:  "inputs:\n  - a (string)\n",
+			pos:  "t.itml:3:1",
+			msg:  "missing intent declaration",
+		},
+		{
+			name: "unknown section",
+			src:  "intent \"x\"\nsteps:\n",
+			pos:  "t.itml:2:1",
+			msg:  `unknown section "steps"`,
+		},
+		{
+			name: "missing type",
+			src:  "intent \"x\"\ninputs:\n  - name string\n",
+			pos:  "t.itml:3:10",
+			msg:  "expected '('",
+		},
+		{
+			name: "unterminated string",
+			src:  "intent \"x\"\nworkflow:\n  → log(\"oops)\n",
+			pos:  "t.itml:3:9",
+			msg:  "unterminated string literal",
+		},
+		{
+			name: "unclosed call",
+			src:  "intent \"x\"\nworkflow:\n  → log(\"a\"\n",
+			pos:  "t.itml:4:1",
+			msg:  "to close argument list",
+		},
+		{
+			name: "else without if",
+			src:  "intent \"x\"\nworkflow:\n  → log(\"a\")\n  else:\n    → log(\"b\")\n",
+			pos:  "t.itml:4:3",
+			msg:  "else without a matching if",
+		},
+		{
+			name: "empty if block",
+			src:  "intent \"x\"\nworkflow:\n  → if ok:\n  → log(\"a\")\n",
+			pos:  "t.itml:3:3",
+			msg:  `expected an indented block after "if ok:"`,
+		},
+		{
+			name: "missing colon",
+			src:  "intent \"x\"\nworkflow:\n  → for item in items\n    → log(item)\n",
+			pos:  "t.itml:3:22",
+			msg:  "after for loop",
+		},
+		{
+			name: "stray line in section",
+			src:  "intent \"x\"\nworkflow:\n  → log(\"a\")\n  - b (string)\n",
+			pos:  "t.itml:4:3",
+			msg:  "in workflow section",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile("t.itml", []byte(tt.src))
+			var diag *Diagnostic
+			if !errors.As(err, &diag) {
+				t.Fatalf("Expected *Diagnostic, got %v", err)
+			}
+			if diag.Pos.String() != tt.pos {
+				t.Errorf("Expected position %s, got %s", tt.pos, diag.Pos)
+			}
+			if !strings.Contains(diag.Msg, tt.msg) {
+				t.Errorf("Expected message containing %q, got %q", tt.msg, diag.Msg)
+			}
+		})
+	}
+}
+
+func TestParseITML_DSLMetadataAndOutputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analyzer.itml")
+	src := `intent "Text Analyzer"
+description: "Analyze text"
+itmlVersion: "0.1"
+version: "2.1.0"
+author: "Acme"
+license: "Apache-2.0"
+tags: ["text", "nlp"]
+cache: "30m"
+
+outputs:
+  - word_count (number) description="Number of words"
+  - summary (string) format="markdown"
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	intent, err := ParseITML(path)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+
+	if intent.Description != "Analyze text" || intent.ItmlVersion != "0.1" || intent.Version != "2.1.0" {
+		t.Errorf("Unexpected metadata: %q %q %q", intent.Description, intent.ItmlVersion, intent.Version)
+	}
+	if intent.Author != "Acme" || intent.License != "Apache-2.0" {
+		t.Errorf("Unexpected author/license: %q %q", intent.Author, intent.License)
+	}
+	if strings.Join(intent.Tags, ",") != "text,nlp" {
+		t.Errorf("Unexpected tags: %v", intent.Tags)
+	}
+	if intent.Path != path {
+		t.Errorf("Expected path %s, got %q", path, intent.Path)
+	}
+	if ttl, err := intent.CacheTTL(); err != nil || ttl != 30*time.Minute {
+		t.Errorf("Expected a 30m cache duration, got %v, %v", ttl, err)
+	}
+
+	if len(intent.Outputs) != 2 {
+		t.Fatalf("Expected 2 declared outputs, got %+v", intent.Outputs)
+	}
+	if intent.Outputs[0].Name != "word_count" || intent.Outputs[0].Type != "number" || intent.Outputs[0].Description != "Number of words" {
+		t.Errorf("Unexpected first output: %+v", intent.Outputs[0])
+	}
+	if intent.Outputs[1].Format != "markdown" {
+		t.Errorf("Expected markdown format, got %+v", intent.Outputs[1])
+	}
+}
+
+func TestParseFile_MetadataDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		msg  string
+	}{
+		{"unknown key", "intent \"x\"\ncolour: \"red\"\n", `unknown key "colour"`},
+		{"duplicate key", "intent \"x\"\nauthor: \"a\"\nauthor: \"b\"\n", `duplicate "author"`},
+		{"non-string value", "intent \"x\"\ndescription: [1]\n", "must be a string"},
+		{"unknown output attribute", "intent \"x\"\noutputs:\n  - a (string) shape=\"round\"\n", `unknown attribute "shape"`},
+		{"reserved loop variable", "intent \"x\"\nworkflow:\n  → for result in items:\n    → log(result)\n", `step name "result" is reserved`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFile("t.itml", []byte(tt.src))
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			_, err = buildIntent(f)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Expected error containing %q, got %v", tt.msg, err)
+			}
+		})
+	}
+}
+
+func TestParseITML_DSLWorkflowScript(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analyzer.itml")
+	src := `intent "Analyzer"
+workflow:
+  → split(text, " ")
+  → transform(input, {
+      summary: text.substring(0, 100)
+    })
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	intent, err := ParseITML(path)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	want := "→ split(text, \" \")\n→ transform(input, { summary: text.substring(0, 100) })"
+	if intent.Script != want {
+		t.Errorf("Expected script %q, got %q", want, intent.Script)
+	}
+}
+
+func TestParseWorkflow_ControlFlow(t *testing.T) {
+	src := `intent "Triage"
+workflow:
+  → for item in items:
+    → if item.priority > 2:
+      → log("urgent")
+    else if item.priority > 1:
+      → log("normal")
+    else:
+      → log("low")
+  → if = 1
+  → return(count=if)
+`
+	f, err := ParseFile("triage.itml", []byte(src))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	steps := f.Workflow.Steps
+	if len(steps) != 3 {
+		t.Fatalf("Expected 3 top-level steps, got %d", len(steps))
+	}
+	loop := steps[0]
+	if loop.Kind != StepFor || loop.Var != "item" || loop.Expr.String() != "items" {
+		t.Errorf("Expected for loop over items, got %q", loop)
+	}
+	if len(loop.Body) != 1 || loop.Body[0].Kind != StepIf {
+		t.Fatalf("Expected loop body with one if, got %d steps", len(loop.Body))
+	}
+	cond := loop.Body[0]
+	if len(cond.Else) != 1 || cond.Else[0].Kind != StepIf || len(cond.Else[0].Else) != 1 {
+		t.Errorf("Expected else if chain ending in else, got %v", cond.Else)
+	}
+	if steps[1].Kind != StepExpr || steps[1].Name != "if" {
+		t.Errorf("Expected step named 'if', got %q", steps[1])
+	}
+
+	script := FormatWorkflow(steps)
+	want := `→ for item in items:
+  → if item.priority > 2:
+    → log("urgent")
+  else if item.priority > 1:
+    → log("normal")
+  else:
+    → log("low")
+→ if = 1
+→ return(count=if)`
+	if script != want {
+		t.Errorf("Expected script:\n%s\ngot:\n%s", want, script)
+	}
+
+	reparsed, err := ParseWorkflow("workflow", script)
+	if err != nil {
+		t.Fatalf("ParseWorkflow failed: %v", err)
+	}
+	if got := FormatWorkflow(reparsed); got != script {
+		t.Errorf("Expected round trip to give:\n%s\ngot:\n%s", script, got)
+	}
+}
+
+func TestParseWorkflow_StepOptions(t *testing.T) {
+	script := `→ try:
+  → data = http.get(url) retry=3 backoff="1s" timeout="10s" on_error=fallback({})
+  → log("fetched") on_error=continue
+catch err:
+  → log(err.message)`
+	steps, err := ParseWorkflow("workflow", script)
+	if err != nil {
+		t.Fatalf("ParseWorkflow failed: %v", err)
+	}
+	if len(steps) != 1 || steps[0].Kind != StepTry || steps[0].Var != "err" || len(steps[0].Else) != 1 {
+		t.Fatalf("Expected try with catch err, got %v", steps)
+	}
+
+	opts := steps[0].Body[0].Options
+	if opts.Retry != 3 || opts.Backoff != time.Second || opts.Timeout != 10*time.Second {
+		t.Errorf("Unexpected retry options: %+v", opts)
+	}
+	if opts.OnError != OnErrorFallback || opts.Fallback.String() != "{}" {
+		t.Errorf("Expected fallback({}), got %s %v", opts.OnError, opts.Fallback)
+	}
+	if opts := steps[0].Body[1].Options; opts.OnError != OnErrorContinue || opts.Retry != 0 || opts.Backoff != DefaultRetryBackoff {
+		t.Errorf("Unexpected default options: %+v", opts)
+	}
+	if got := FormatWorkflow(steps); got != script {
+		t.Errorf("Expected round trip to give:\n%s\ngot:\n%s", script, got)
+	}
+
+	tests := []struct {
+		script string
+		msg    string
+	}{
+		{`→ log("a") retry=-1`, `"retry" must be a whole number of retries`},
+		{`→ log("a") timeout="soon"`, `"timeout" must be a duration`},
+		{`→ log("a") retry=1 retry=2`, `duplicate step attribute "retry"`},
+		{`→ log("a") on_error`, `step attribute "on_error" requires a value`},
+		{"→ log(\"a\")\ncatch:\n  → log(\"b\")", "catch without a matching try"},
+	}
+	for _, tt := range tests {
+		_, err := ParseWorkflow("workflow", tt.script)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.script, tt.msg, err)
+		}
+	}
+}
+
+func TestParseWorkflow_Parallel(t *testing.T) {
+	script := `→ pages = parallel limit=2 fail_fast=false:
+  → a = http.get(first)
+  → if ready:
+    → b = http.get(second)
+→ parallel:
+  → log("x")`
+	steps, err := ParseWorkflow("workflow", script)
+	if err != nil {
+		t.Fatalf("ParseWorkflow failed: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Kind != StepParallel || steps[0].Name != "pages" || len(steps[0].Body) != 2 {
+		t.Fatalf("Expected a named parallel step with 2 branches, got %v", steps)
+	}
+	if opts := steps[0].Options; opts.Limit != 2 || opts.FailFast {
+		t.Errorf("Expected limit=2 fail_fast=false, got %+v", opts)
+	}
+	if opts := steps[1].Options; opts.Limit != 0 || !opts.FailFast {
+		t.Errorf("Expected unlimited fail-fast defaults, got %+v", opts)
+	}
+	if got := FormatWorkflow(steps); got != script {
+		t.Errorf("Expected round trip to give:\n%s\ngot:\n%s", script, got)
+	}
+
+	tests := []struct {
+		script string
+		msg    string
+	}{
+		{"→ parallel limit=0:\n  → log(\"a\")", `"limit" must be a whole number of branches`},
+		{"→ parallel fail_fast=\"no\":\n  → log(\"a\")", `"fail_fast" must be true or false`},
+		{"→ parallel retry=2:\n  → log(\"a\")", `unknown parallel attribute "retry"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseWorkflow("workflow", tt.script)
+		if err == nil || !strings.Contains(err.Error(), tt.msg) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.script, tt.msg, err)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"json object", "\n  {\"name\": \"x\"}", FormatJSON, false},
+		{"dsl", "# comment\nintent \"x\"\n", FormatDSL, false},
+		{"dsl with leading metadata", "description: \"d\"\nintent \"x\"\n", FormatDSL, false},
+		{"yaml", "name: x\nversion: 1.0.0\n", FormatYAML, false},
+		{"explicit header", "# format: yaml\nintent: \"x\"\n", FormatYAML, false},
+		{"header before json", "# format: json\n{}", FormatJSON, false},
+		{"unknown header", "# format: toml\n", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFormat([]byte(tt.content))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got format %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseITML_AggregatedDiagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.itml")
+	src := `intent "Broken"
+colour: "red"
+
+inputs:
+  - a (strng)
+  - b string
+  - a (string)
+
+workflow:
+  → log("unterminated)
+  → return(status="ok")
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	_, err := ParseITML(path)
+	var diags Diagnostics
+	if !errors.As(err, &diags) {
+		t.Fatalf("Expected Diagnostics, got %v", err)
+	}
+
+	want := []string{
+		":2:1: unknown key \"colour\"",
+		":5:8: invalid type \"strng\"",
+		":6:7: expected '('",
+		":7:3: duplicate input \"a\"",
+		":10:9: unterminated string literal",
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("Expected diagnostic %q in:\n%v", w, err)
+		}
+	}
+	if len(diags) != len(want) {
+		t.Errorf("Expected %d diagnostics, got %d:\n%v", len(want), len(diags), err)
+	}
+	for i := 1; i < len(diags); i++ {
+		if diags[i].Pos.Line < diags[i-1].Pos.Line {
+			t.Errorf("Diagnostics not sorted by position:\n%v", err)
+		}
+	}
+	if strings.Contains(err.Error(), "YAML") || strings.Contains(err.Error(), "JSON") {
+		t.Errorf("DSL errors must not fall back to other formats: %v", err)
+	}
+}
+
+func TestParseITML_JSONSyntaxPosition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.itml")
+	if err := os.WriteFile(path, []byte("{\n  \"name\": \"x\",\n  \"version\" \"1\"\n}"), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	_, err := ParseITML(path)
+	var diag *Diagnostic
+	if !errors.As(err, &diag) {
+		t.Fatalf("Expected *Diagnostic, got %v", err)
+	}
+	if diag.Pos.Line != 3 {
+		t.Errorf("Expected error on line 3, got %s", diag.Pos)
+	}
+}
+
+func TestParseITML_DSLParameterAttributes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather.itml")
+	src := `intent "Weather"
+inputs:
+  - city (string) required description="City name" pattern="^[A-Za-z ]+$"
+  - days (integer) min=1 max=14 default="3"
+  - units (string) enum=["metric", "imperial"] default="metric"
+  - verbose (boolean) required=false
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+
+	intent, err := ParseITML(path)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	if len(intent.Parameters) != 4 {
+		t.Fatalf("Expected 4 parameters, got %d", len(intent.Parameters))
+	}
+
+	city := intent.Parameters[0]
+	if !city.Required || city.Description != "City name" || city.Validation.Pattern != "^[A-Za-z ]+$" {
+		t.Errorf("Unexpected city parameter: %+v", city)
+	}
+
+	days := intent.Parameters[1]
+	if days.Required {
+		t.Errorf("Expected days to be optional")
+	}
+	if days.Validation.Min == nil || *days.Validation.Min != 1 || days.Validation.Max == nil || *days.Validation.Max != 14 {
+		t.Errorf("Unexpected days bounds: %+v", days.Validation)
+	}
+
+	units := intent.Parameters[2]
+	if strings.Join(units.Validation.Options, ",") != "metric,imperial" {
+		t.Errorf("Unexpected units options: %v", units.Validation.Options)
+	}
+
+	if intent.Parameters[3].Required {
+		t.Errorf("Expected required=false to leave verbose optional")
+	}
+}
+
+func TestParseFile_ParameterAttributeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		attr string
+		msg  string
+	}{
+		{"unknown attribute", "colour=\"red\"", `unknown attribute "colour"`},
+		{"missing value", "min", `requires a value`},
+		{"non-numeric bound", "max=\"ten\"", `"max" must be a number`},
+		{"inverted bounds", "min=5 max=1", "greater than max"},
+		{"bad required", "required=\"yes\"", "must be true or false"},
+		{"duplicate", "options=[\"a\"] enum=[\"b\"]", `duplicate attribute "enum"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "intent \"x\"\ninputs:\n  - a (number) " + tt.attr + "\n"
+			f, err := ParseFile("t.itml", []byte(src))
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			_, err = buildIntent(f)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Expected error containing %q, got %v", tt.msg, err)
+			}
+		})
+	}
+}
+
+func TestParseFile_OutputAttributeDiagnostics(t *testing.T) {
+	tests := []struct {
+		name string
+		attr string
+		msg  string
+	}{
+		{"unknown attribute", "colour=\"red\"", `unknown attribute "colour" for output "a"`},
+		{"missing value", "format", `attribute "format" of output "a" requires a value`},
+		{"duplicate", "description=\"one\" description=\"two\"", `duplicate attribute "description" for output "a" (first declared at t.itml:3:`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "intent \"x\"\noutputs:\n  - a (string) " + tt.attr + "\n"
+			f, err := ParseFile("t.itml", []byte(src))
+			if err != nil {
+				t.Fatalf("ParseFile failed: %v", err)
+			}
+			_, err = buildIntent(f)
+			if err == nil || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Expected error containing %q, got %v", tt.msg, err)
+			}
+		})
+	}
+}
+
+func TestParseExpr_Operators(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"1 + 2 * 3", "1 + 2 * 3"},
+		{"(1 + 2) * 3", "(1 + 2) * 3"},
+		{"a - (b - c)", "a - (b - c)"},
+		{"a || b && !c", "a || b && !c"},
+		{"x.length >= 3 == true", "x.length >= 3 == true"},
+		{"-n + -1", "-n + -1"},
+		{"(a + b).length", "(a + b).length"},
+		{"name | upper | truncate(10)", "name | upper | truncate(10)"},
+		{"a + b | join(\", \")", "a + b | join(\", \")"},
+		{"filter(words, . != \"\")", "filter(words, . != \"\")"},
+		{"map(items, .name)", "map(items, .name)"},
+	}
+	for _, tt := range tests {
+		expr, err := ParseExpr("", tt.src)
+		if err != nil {
+			t.Errorf("ParseExpr(%q) failed: %v", tt.src, err)
+			continue
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("ParseExpr(%q).String() = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+
+	expr, err := ParseExpr("", "1 + 2 * 3")
+	if err != nil {
+		t.Fatalf("ParseExpr failed: %v", err)
+	}
+	sum, ok := expr.(*BinaryExpr)
+	if !ok || sum.Op != "+" {
+		t.Fatalf("Expected '+' at the root, got %#v", expr)
+	}
+	if mul, ok := sum.Y.(*BinaryExpr); !ok || mul.Op != "*" {
+		t.Errorf("Expected '*' to bind tighter than '+', got %#v", sum.Y)
+	}
+}
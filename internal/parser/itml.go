@@ -1,11 +1,16 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"gopkg.in/yaml.v3"
 )
@@ -18,10 +23,13 @@ type Intent struct {
 	Author      string                 `json:"author" yaml:"author"`
 	License     string                 `json:"license" yaml:"license"`
 	Tags        []string               `json:"tags" yaml:"tags"`
+	ItmlVersion string                 `json:"itmlVersion,omitempty" yaml:"itmlVersion,omitempty"`
 	Parameters  []Parameter            `json:"parameters" yaml:"parameters"`
 	Outputs     []Output               `json:"outputs" yaml:"outputs"`
 	Examples    []Example              `json:"examples" yaml:"examples"`
 	Script      string                 `json:"script,omitempty" yaml:"script,omitempty"`
+	Cache       string                 `json:"cache,omitempty" yaml:"cache,omitempty"` // how long results may be reused, e.g. "10m"
+	Path        string                 `json:"-" yaml:"-"`                             // the file the intent was parsed from
 	Config      map[string]interface{} `json:"config,omitempty" yaml:"config,omitempty"`
 }
 
@@ -55,6 +63,25 @@ type Validation struct {
 	Max     *float64 `json:"max,omitempty" yaml:"max,omitempty"`
 	Pattern string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
 	Options []string `json:"options,omitempty" yaml:"options,omitempty"`
+
+	pattern *regexp.Regexp // Pattern compiled at parse time
+}
+
+// PatternRegexp returns the compiled Pattern, or nil when no pattern is set.
+// Patterns are compiled once while parsing; intents built in code are
+// compiled on first use.
+func (v *Validation) PatternRegexp() (*regexp.Regexp, error) {
+	if v.Pattern == "" {
+		return nil, nil
+	}
+	if v.pattern == nil || v.pattern.String() != v.Pattern {
+		re, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", v.Pattern, err)
+		}
+		v.pattern = re
+	}
+	return v.pattern, nil
 }
 
 // ParseITML parses an .itml file and returns an Intent struct
@@ -74,112 +101,100 @@ func ParseITML(filename string) (*Intent, error) {
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
 	case ".itml":
-		return parseITMLFormat(content)
+		intent, err := parseITMLFormat(filename, content)
+		if err != nil {
+			return nil, err
+		}
+		intent.Path = filename
+		return intent, nil
 	default:
 		return nil, fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
 
-// parseITMLFormat parses the .itml format
-func parseITMLFormat(content []byte) (*Intent, error) {
-	// First, try to parse as custom ITML format
-	if intent, err := parseCustomITMLFormat(content); err == nil {
-		return intent, nil
-	}
-	
-	// If custom ITML parsing fails, try JSON
-	var intent Intent
-	if err := json.Unmarshal(content, &intent); err == nil {
-		// Validate the parsed intent
-		if err := validateIntent(&intent); err != nil {
-			return nil, fmt.Errorf("invalid intent format: %w", err)
-		}
-		return &intent, nil
-	}
-	
-	// If JSON parsing fails, try YAML
-	return parseYAMLFormat(content)
-}
+// Source formats accepted in .itml files
+const (
+	FormatDSL  = "dsl"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
 
-// parseCustomITMLFormat parses the custom ITML DSL format
-func parseCustomITMLFormat(content []byte) (*Intent, error) {
+var formatHeader = regexp.MustCompile(`^#\s*format:\s*(\S+)\s*$`)
+var dslIntentLine = regexp.MustCompile(`^intent\s+["']`)
+
+// DetectFormat determines how an .itml file is encoded. An explicit
+// `# format: dsl|json|yaml` header on the first non-blank line wins;
+// otherwise a leading '{' or '[' means JSON, an unindented
+// `intent "Name"` line means DSL, and anything else is YAML.
+func DetectFormat(content []byte) (string, error) {
 	lines := strings.Split(string(content), "\n")
-	
-	intent := &Intent{
-		Version:     "1.0.0",
-		Author:      "Unknown",
-		License:     "MIT",
-		Parameters:  []Parameter{},
-		Outputs:     []Output{},
-		Examples:    []Example{},
-		Config:      make(map[string]interface{}),
-	}
-	
-	var currentSection string
-	var workflowSteps []string
-	
-	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
+
+	format := ""
+	for _, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if line == "" {
 			continue
 		}
-		
-		// Parse intent declaration
-		if strings.HasPrefix(line, "intent ") {
-			// Extract intent name from quotes
-			if strings.Contains(line, `"`) {
-				start := strings.Index(line, `"`)
-				end := strings.LastIndex(line, `"`)
-				if start != -1 && end != -1 && end > start {
-					intent.Name = line[start+1:end]
-					intent.Description = intent.Name // Use name as description for now
-				} else {
-					return nil, fmt.Errorf("invalid intent declaration on line %d: %s", i+1, line)
-				}
-			} else {
-				return nil, fmt.Errorf("intent name must be quoted on line %d: %s", i+1, line)
+		if m := formatHeader.FindStringSubmatch(line); m != nil {
+			switch f := strings.ToLower(m[1]); f {
+			case FormatDSL, FormatJSON, FormatYAML:
+				return f, nil
+			default:
+				return "", fmt.Errorf("unknown format %q in header (expected dsl, json or yaml)", m[1])
 			}
-			continue
 		}
-		
-		// Parse section headers
-		if strings.HasSuffix(line, ":") {
-			currentSection = strings.TrimSuffix(line, ":")
+		if strings.HasPrefix(line, "#") {
 			continue
 		}
-		
-		// Parse inputs section
-		if currentSection == "inputs" {
-			if strings.HasPrefix(line, "- ") {
-				param, err := parseParameter(line[2:])
-				if err != nil {
-					return nil, fmt.Errorf("invalid parameter on line %d: %w", i+1, err)
-				}
-				intent.Parameters = append(intent.Parameters, param)
-			}
-			continue
+		if strings.HasPrefix(line, "{") || strings.HasPrefix(line, "[") {
+			return FormatJSON, nil
 		}
-		
-		// Parse workflow section
-		if currentSection == "workflow" {
-			if strings.HasPrefix(line, "→ ") {
-				workflowSteps = append(workflowSteps, line[2:])
-			}
-			continue
+		break
+	}
+
+	for _, line := range lines {
+		if dslIntentLine.MatchString(strings.TrimRight(line, "\r")) {
+			format = FormatDSL
+			break
 		}
 	}
+	if format == "" {
+		format = FormatYAML
+	}
+	return format, nil
+}
+
+// parseITMLFormat parses the .itml format using the single parser that
+// matches the detected encoding
+func parseITMLFormat(filename string, content []byte) (*Intent, error) {
+	format, err := DetectFormat(content)
+	if err != nil {
+		return nil, &Diagnostic{Pos: Pos{File: filename, Line: 1, Col: 1}, Msg: err.Error()}
+	}
 	
-	// Convert workflow steps to script
-	if len(workflowSteps) > 0 {
-		intent.Script = strings.Join(workflowSteps, "\n")
+	switch format {
+	case FormatDSL:
+		return parseCustomITMLFormat(filename, content)
+	case FormatJSON:
+		return parseJSONFormat(filename, content)
+	default:
+		return parseYAMLFormat(filename, content)
 	}
+}
+
+// parseCustomITMLFormat parses the custom ITML DSL format
+func parseCustomITMLFormat(filename string, content []byte) (*Intent, error) {
+	file, diags := parseDSL(filename, content)
 	
-	// Add default outputs if none specified
-	if len(intent.Outputs) == 0 {
-		intent.Outputs = []Output{
-			{Name: "result", Type: "string", Description: "Execution result"},
-			{Name: "status", Type: "string", Description: "Execution status"},
-		}
+	var intent *Intent
+	if file.Intent != nil {
+		built, err := buildIntent(file)
+		diags.addErr(err)
+		intent = built
+	}
+	if len(diags) > 0 {
+		diags.sortByPos()
+		return nil, diags
 	}
 	
 	// Validate the parsed intent
@@ -190,61 +205,35 @@ func parseCustomITMLFormat(content []byte) (*Intent, error) {
 	return intent, nil
 }
 
-// parseParameter parses a parameter definition like "name (string) default=\"World\""
-func parseParameter(paramStr string) (Parameter, error) {
-	param := Parameter{
-		Required: false,
-	}
-	
-	// Extract parameter name
-	parts := strings.Fields(paramStr)
-	if len(parts) == 0 {
-		return param, fmt.Errorf("empty parameter definition")
-	}
-	
-	param.Name = parts[0]
-	
-	// Extract type from parentheses
-	for _, part := range parts {
-		if strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")") {
-			param.Type = strings.Trim(part, "()")
-			break
+// parseJSONFormat parses the JSON encoding of an intent
+func parseJSONFormat(filename string, content []byte) (*Intent, error) {
+	var intent Intent
+	if err := json.Unmarshal(stripFormatHeader(content), &intent); err != nil {
+		pos := Pos{File: filename}
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &syntaxErr):
+			pos = offsetPos(filename, content, syntaxErr.Offset)
+		case errors.As(err, &typeErr):
+			pos = offsetPos(filename, content, typeErr.Offset)
 		}
+		return nil, &Diagnostic{Pos: pos, Msg: fmt.Sprintf("failed to parse JSON: %v", err)}
 	}
 	
-	// Extract default value
-	if strings.Contains(paramStr, "default=") {
-		start := strings.Index(paramStr, "default=")
-		if start != -1 {
-			valuePart := paramStr[start+8:] // Skip "default="
-			if strings.HasPrefix(valuePart, `"`) {
-				end := strings.Index(valuePart[1:], `"`)
-				if end != -1 {
-					param.Default = valuePart[1:end+1]
-				}
-			} else {
-				// Handle unquoted default values
-				spaceIndex := strings.Index(valuePart, " ")
-				if spaceIndex != -1 {
-					param.Default = valuePart[:spaceIndex]
-				} else {
-					param.Default = valuePart
-				}
-			}
-		}
+	// Validate the parsed intent
+	if err := validateIntent(&intent); err != nil {
+		return nil, fmt.Errorf("invalid intent format: %w", err)
 	}
 	
-	// Set description
-	param.Description = fmt.Sprintf("Parameter %s", param.Name)
-	
-	return param, nil
+	return &intent, nil
 }
 
-// parseYAMLFormat parses YAML format (fallback)
-func parseYAMLFormat(content []byte) (*Intent, error) {
+// parseYAMLFormat parses the YAML encoding of an intent
+func parseYAMLFormat(filename string, content []byte) (*Intent, error) {
 	var intent Intent
 	if err := yaml.Unmarshal(content, &intent); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		return nil, &Diagnostic{Pos: Pos{File: filename}, Msg: fmt.Sprintf("failed to parse YAML: %v", err)}
 	}
 	
 	// Validate the parsed intent
@@ -255,54 +244,128 @@ func parseYAMLFormat(content []byte) (*Intent, error) {
 	return &intent, nil
 }
 
-// validateIntent validates the parsed intent structure
+// stripFormatHeader blanks out a leading `# format:` comment so JSON
+// decoders accept the file while byte offsets stay unchanged
+func stripFormatHeader(content []byte) []byte {
+	out := append([]byte(nil), content...)
+	start := 0
+	for start < len(out) {
+		end := bytes.IndexByte(out[start:], '\n')
+		if end < 0 {
+			end = len(out) - start
+		}
+		line := strings.TrimSpace(string(out[start : start+end]))
+		if line != "" {
+			if formatHeader.MatchString(line) {
+				for i := start; i < start+end; i++ {
+					out[i] = ' '
+				}
+			}
+			break
+		}
+		start += end + 1
+	}
+	return out
+}
+
+// offsetPos converts a byte offset into a line/column position
+func offsetPos(filename string, content []byte, offset int64) Pos {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	col := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+	return Pos{File: filename, Line: line, Col: col}
+}
+
+// validateIntent validates the parsed intent structure, reporting every
+// problem rather than stopping at the first
 func validateIntent(intent *Intent) error {
+	var diags Diagnostics
+	
 	if intent.Name == "" {
-		return fmt.Errorf("intent name is required")
+		diags.add(Pos{}, "intent name is required")
 	}
 	
 	if intent.Version == "" {
-		return fmt.Errorf("intent version is required")
+		diags.add(Pos{}, "intent version is required")
 	}
 	
 	if intent.Description == "" {
-		return fmt.Errorf("intent description is required")
+		diags.add(Pos{}, "intent description is required")
 	}
 	
-	// Validate parameters
+	// Validate parameters and coerce defaults to their declared type
 	for i, param := range intent.Parameters {
 		if param.Name == "" {
-			return fmt.Errorf("parameter %d: name is required", i)
+			diags.add(Pos{}, "parameter %d: name is required", i)
 		}
 		if param.Type == "" {
-			return fmt.Errorf("parameter %d: type is required", i)
+			diags.add(Pos{}, "parameter %d: type is required", i)
+		} else if !isValidType(param.Type) {
+			diags.add(Pos{}, "parameter %d: invalid type '%s'", i, param.Type)
+		} else if param.Type == "secret" && param.Default != nil {
+			diags.add(Pos{}, "parameter %d: %s", i, errSecretDefault)
+		} else if param.Default != nil {
+			value, err := CoerceValue(param.Default, param.Type)
+			if err != nil {
+				diags.add(Pos{}, "parameter %d: invalid default: %v", i, err)
+			} else {
+				intent.Parameters[i].Default = value
+			}
 		}
-		if !isValidType(param.Type) {
-			return fmt.Errorf("parameter %d: invalid type '%s'", i, param.Type)
+		if _, err := intent.Parameters[i].Validation.PatternRegexp(); err != nil {
+			diags.add(Pos{}, "parameter %d: %v", i, err)
 		}
 	}
 	
 	// Validate outputs
 	for i, output := range intent.Outputs {
 		if output.Name == "" {
-			return fmt.Errorf("output %d: name is required", i)
+			diags.add(Pos{}, "output %d: name is required", i)
 		}
 		if output.Type == "" {
-			return fmt.Errorf("output %d: type is required", i)
-		}
-		if !isValidType(output.Type) {
-			return fmt.Errorf("output %d: invalid type '%s'", i, output.Type)
+			diags.add(Pos{}, "output %d: type is required", i)
+		} else if !isValidType(output.Type) {
+			diags.add(Pos{}, "output %d: invalid type '%s'", i, output.Type)
+		} else if output.Type == "secret" {
+			diags.add(Pos{}, "output %d: %s", i, errSecretOutput)
 		}
 	}
 	
-	return nil
+	if _, err := intent.CacheTTL(); err != nil {
+		diags.add(Pos{}, "%v", err)
+	}
+	
+	return diags.Err()
 }
 
+// CacheTTL returns how long the results of the intent may be reused, or 0
+// when it does not declare a cache duration
+func (i *Intent) CacheTTL() (time.Duration, error) {
+	if i.Cache == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(i.Cache)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid cache duration %q (expected a positive duration such as 10m or 24h)", i.Cache)
+	}
+	return ttl, nil
+}
+
+// Secrets are only ever read from inputs, so that their values never
+// appear in an intent file or its results
+const (
+	errSecretDefault = "a secret cannot have a default (store it with 'intent secret set' instead)"
+	errSecretOutput  = "secret is only a type for inputs"
+)
+
 // isValidType checks if a type is valid
 func isValidType(t string) bool {
 	validTypes := []string{
 		"string", "number", "boolean", "array", "object",
-		"integer", "float", "text", "json", "file", "url",
+		"integer", "float", "text", "json", "file", "url", "secret",
 	}
 	
 	for _, validType := range validTypes {
@@ -331,41 +394,40 @@ func (i *Intent) GetParameterValue(name string, inputParams map[string]string) (
 	// Get the input value
 	inputValue, exists := inputParams[name]
 	if !exists {
-		if param.Required {
+		if param.Required && param.Default == nil {
 			return nil, fmt.Errorf("required parameter '%s' not provided", name)
 		}
 		return param.Default, nil
 	}
 	
 	// Convert to appropriate type
-	return convertToType(inputValue, param.Type)
+	return ConvertValue(inputValue, param.Type)
 }
 
-// convertToType converts a string value to the specified type
-func convertToType(value, targetType string) (interface{}, error) {
-	switch targetType {
-	case "string", "text":
-		return value, nil
-	case "number", "integer", "float":
-		// Simple number parsing - in a real implementation, you'd use strconv
-		return value, nil // For now, return as string
-	case "boolean":
-		return strings.ToLower(value) == "true", nil
-	case "array":
-		// Parse as JSON array
-		var result []interface{}
-		if err := json.Unmarshal([]byte(value), &result); err != nil {
-			return nil, fmt.Errorf("invalid array format: %w", err)
+// SecretParameters returns the names of the parameters of type secret
+func (i *Intent) SecretParameters() []string {
+	var names []string
+	for _, param := range i.Parameters {
+		if param.Type == "secret" {
+			names = append(names, param.Name)
 		}
-		return result, nil
-	case "object", "json":
-		// Parse as JSON object
-		var result map[string]interface{}
-		if err := json.Unmarshal([]byte(value), &result); err != nil {
-			return nil, fmt.Errorf("invalid object format: %w", err)
+	}
+	return names
+}
+
+// ResolveParameters returns typed values for every declared parameter,
+// using defaults for inputs that were not provided. Inputs that do not
+// match a declared parameter are ignored.
+func (i *Intent) ResolveParameters(inputParams map[string]string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for _, param := range i.Parameters {
+		value, err := i.GetParameterValue(param.Name, inputParams)
+		if err != nil {
+			return nil, fmt.Errorf("parameter '%s': %w", param.Name, err)
+		}
+		if value != nil {
+			values[param.Name] = value
 		}
-		return result, nil
-	default:
-		return value, nil
 	}
+	return values, nil
 }
@@ -0,0 +1,337 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNewline
+	tokIdent
+	tokString
+	tokNumber
+	tokArrow
+	tokMinus
+	tokColon
+	tokComma
+	tokDot
+	tokAssign
+	tokLParen
+	tokRParen
+	tokLBrace
+	tokRBrace
+	tokLBracket
+	tokRBracket
+	tokPlus
+	tokStar
+	tokSlash
+	tokPercent
+	tokEq
+	tokNeq
+	tokLt
+	tokLe
+	tokGt
+	tokGe
+	tokAnd
+	tokOr
+	tokNot
+	tokPipe
+)
+
+var tokenNames = map[tokenKind]string{
+	tokEOF:      "end of file",
+	tokNewline:  "newline",
+	tokIdent:    "identifier",
+	tokString:   "string",
+	tokNumber:   "number",
+	tokArrow:    "'→'",
+	tokMinus:    "'-'",
+	tokColon:    "':'",
+	tokComma:    "','",
+	tokDot:      "'.'",
+	tokAssign:   "'='",
+	tokLParen:   "'('",
+	tokRParen:   "')'",
+	tokLBrace:   "'{'",
+	tokRBrace:   "'}'",
+	tokLBracket: "'['",
+	tokRBracket: "']'",
+	tokPlus:     "'+'",
+	tokStar:     "'*'",
+	tokSlash:    "'/'",
+	tokPercent:  "'%'",
+	tokEq:       "'=='",
+	tokNeq:      "'!='",
+	tokLt:       "'<'",
+	tokLe:       "'<='",
+	tokGt:       "'>'",
+	tokGe:       "'>='",
+	tokAnd:      "'&&'",
+	tokOr:       "'||'",
+	tokNot:      "'!'",
+	tokPipe:     "'|'",
+}
+
+func (k tokenKind) String() string {
+	if name, ok := tokenNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("token(%d)", int(k))
+}
+
+// token is a single lexical element. For strings, text holds the unquoted value.
+type token struct {
+	kind tokenKind
+	text string
+	pos  Pos
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokIdent, tokNumber:
+		return fmt.Sprintf("%s %q", t.kind, t.text)
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return t.kind.String()
+	}
+}
+
+// lexer turns ITML source into tokens. Newlines inside brackets are
+// skipped so that expressions may span several lines.
+type lexer struct {
+	file  string
+	src   string
+	off   int
+	line  int
+	col   int
+	depth int
+	toks  []token
+	diags Diagnostics
+}
+
+// lex tokenizes src. Malformed tokens are reported as diagnostics and
+// skipped so that the parser can still report errors further down.
+func lex(file string, src []byte) ([]token, Diagnostics) {
+	l := &lexer{file: file, src: string(src), line: 1, col: 1}
+	l.run()
+	return l.toks, l.diags
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{File: l.file, Line: l.line, Col: l.col}
+}
+
+func (l *lexer) peek() rune {
+	if l.off >= len(l.src) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+	return r
+}
+
+func (l *lexer) next() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) emit(kind tokenKind, text string, pos Pos) {
+	l.toks = append(l.toks, token{kind: kind, text: text, pos: pos})
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...interface{}) {
+	l.diags.add(pos, format, args...)
+}
+
+func (l *lexer) run() {
+	for l.off < len(l.src) {
+		pos := l.pos()
+		r := l.peek()
+
+		switch {
+		case r == '\n':
+			l.next()
+			if l.depth == 0 && len(l.toks) > 0 && l.toks[len(l.toks)-1].kind != tokNewline {
+				l.emit(tokNewline, "", pos)
+			}
+		case r == ' ' || r == '\t' || r == '\r':
+			l.next()
+		case r == '#':
+			for l.off < len(l.src) && l.peek() != '\n' {
+				l.next()
+			}
+		case r == '"' || r == '\'':
+			if s, ok := l.lexString(); ok {
+				l.emit(tokString, s, pos)
+			}
+		case r >= '0' && r <= '9':
+			l.emit(tokNumber, l.lexNumber(), pos)
+		case r == '_' || unicode.IsLetter(r):
+			start := l.off
+			for l.off < len(l.src) {
+				c := l.peek()
+				if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				l.next()
+			}
+			l.emit(tokIdent, l.src[start:l.off], pos)
+		case r == '→':
+			l.next()
+			l.emit(tokArrow, "→", pos)
+		case r == '-':
+			l.next()
+			if l.peek() == '>' {
+				l.next()
+				l.emit(tokArrow, "->", pos)
+			} else {
+				l.emit(tokMinus, "-", pos)
+			}
+		case l.lexOperator(pos):
+		default:
+			kind, ok := punctuation[r]
+			l.next()
+			if !ok {
+				l.errorf(pos, "unexpected character %q", r)
+				continue
+			}
+			switch kind {
+			case tokLParen, tokLBrace, tokLBracket:
+				l.depth++
+			case tokRParen, tokRBrace, tokRBracket:
+				if l.depth > 0 {
+					l.depth--
+				}
+			}
+			l.emit(kind, string(r), pos)
+		}
+	}
+
+	if len(l.toks) > 0 && l.toks[len(l.toks)-1].kind != tokNewline {
+		l.emit(tokNewline, "", l.pos())
+	}
+	l.emit(tokEOF, "", l.pos())
+}
+
+var punctuation = map[rune]tokenKind{
+	':': tokColon,
+	',': tokComma,
+	'.': tokDot,
+	'=': tokAssign,
+	'(': tokLParen,
+	')': tokRParen,
+	'{': tokLBrace,
+	'}': tokRBrace,
+	'[': tokLBracket,
+	']': tokRBracket,
+}
+
+// operators maps one- and two-character operators to their tokens.
+// Two-character forms are tried first.
+var operators = []struct {
+	text string
+	kind tokenKind
+}{
+	{"==", tokEq},
+	{"!=", tokNeq},
+	{"<=", tokLe},
+	{">=", tokGe},
+	{"&&", tokAnd},
+	{"||", tokOr},
+	{"+", tokPlus},
+	{"*", tokStar},
+	{"/", tokSlash},
+	{"%", tokPercent},
+	{"<", tokLt},
+	{">", tokGt},
+	{"!", tokNot},
+	{"|", tokPipe},
+}
+
+// lexOperator emits an operator token if one starts at the current offset
+func (l *lexer) lexOperator(pos Pos) bool {
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[l.off:], op.text) {
+			for range op.text {
+				l.next()
+			}
+			l.emit(op.kind, op.text, pos)
+			return true
+		}
+	}
+	return false
+}
+
+// lexString reads a quoted string literal and returns its unescaped value.
+// An unterminated literal is reported and consumes the rest of the line.
+func (l *lexer) lexString() (string, bool) {
+	start := l.pos()
+	quote := l.next()
+	var sb strings.Builder
+	for {
+		if l.off >= len(l.src) || l.peek() == '\n' {
+			l.errorf(start, "unterminated string literal")
+			// Brackets opened on this line can no longer be matched reliably;
+			// resetting lets the newline end the statement.
+			l.depth = 0
+			return "", false
+		}
+		r := l.next()
+		if r == quote {
+			return sb.String(), true
+		}
+		if r != '\\' {
+			sb.WriteRune(r)
+			continue
+		}
+		escPos := l.pos()
+		if l.off >= len(l.src) || l.peek() == '\n' {
+			continue
+		}
+		switch e := l.next(); e {
+		case 'n':
+			sb.WriteRune('\n')
+		case 't':
+			sb.WriteRune('\t')
+		case 'r':
+			sb.WriteRune('\r')
+		case '\\', '"', '\'':
+			sb.WriteRune(e)
+		default:
+			l.errorf(escPos, "unknown escape sequence '\\%c'", e)
+		}
+	}
+}
+
+// lexNumber reads an unsigned integer or decimal literal
+func (l *lexer) lexNumber() string {
+	start := l.off
+	for l.off < len(l.src) && isDigit(l.peek()) {
+		l.next()
+	}
+	// Only treat '.' as a decimal point when a digit follows, so that
+	// member access on numbers stays unambiguous.
+	if l.peek() == '.' && l.off+1 < len(l.src) && isDigit(rune(l.src[l.off+1])) {
+		l.next()
+		for l.off < len(l.src) && isDigit(l.peek()) {
+			l.next()
+		}
+	}
+	return l.src[start:l.off]
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
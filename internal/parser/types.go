@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ConvertValue converts a raw string input to the Go representation of
+// the given ITML type: int64 for integer, float64 for number/float, bool
+// for boolean, []interface{} for array and map[string]interface{} for
+// object/json. Malformed input is an error.
+func ConvertValue(value, targetType string) (interface{}, error) {
+	switch targetType {
+	case "string", "text", "secret":
+		return value, nil
+	case "integer":
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %q", value)
+		}
+		return n, nil
+	case "number", "float":
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("invalid number %q", value)
+		}
+		return f, nil
+	case "boolean":
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid boolean %q (expected true or false)", value)
+	case "url":
+		u, err := url.Parse(value)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q (expected scheme://host/...)", value)
+		}
+		return value, nil
+	case "file":
+		if strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("file path cannot be empty")
+		}
+		return value, nil
+	case "array":
+		var result []interface{}
+		if err := json.Unmarshal([]byte(value), &result); err != nil {
+			return nil, fmt.Errorf("invalid array format: %w", err)
+		}
+		return result, nil
+	case "object", "json":
+		var result map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &result); err != nil {
+			return nil, fmt.Errorf("invalid object format: %w", err)
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// CoerceValue converts an already-decoded value (from JSON, YAML or a DSL
+// literal) to the representation ConvertValue would produce for the type.
+// Strings are parsed with ConvertValue; other values must already match.
+func CoerceValue(value interface{}, targetType string) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s, ok := value.(string); ok {
+		return ConvertValue(s, targetType)
+	}
+
+	switch targetType {
+	case "integer":
+		switch n := value.(type) {
+		case int64:
+			return n, nil
+		case int:
+			return int64(n), nil
+		}
+		if f, ok := toFloat(value); ok {
+			if f != math.Trunc(f) {
+				return nil, fmt.Errorf("expected integer, got %v", value)
+			}
+			// int64(f) is implementation-defined outside this range
+			if f >= 1<<63 || f < -(1<<63) {
+				return nil, fmt.Errorf("integer out of range: %v", value)
+			}
+			return int64(f), nil
+		}
+	case "number", "float":
+		if f, ok := toFloat(value); ok {
+			return f, nil
+		}
+	case "boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case "array":
+		if arr, ok := value.([]interface{}); ok {
+			return arr, nil
+		}
+	case "object":
+		if obj, ok := value.(map[string]interface{}); ok {
+			return obj, nil
+		}
+	case "json":
+		return value, nil
+	}
+	return nil, fmt.Errorf("expected %s, got %T", targetType, value)
+}
+
+// toFloat reports the numeric value of any Go number type
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// literalValue evaluates a constant DSL expression (strings, numbers,
+// booleans, null, and arrays/objects of those)
+func literalValue(e Expr) (interface{}, error) {
+	switch v := e.(type) {
+	case *StringLit:
+		return v.Value, nil
+	case *NumberLit:
+		return v.Value, nil
+	case *BoolLit:
+		return v.Value, nil
+	case *NullLit:
+		return nil, nil
+	case *ArrayLit:
+		arr := make([]interface{}, len(v.Elems))
+		for i, el := range v.Elems {
+			val, err := literalValue(el)
+			if err != nil {
+				return nil, err
+			}
+			arr[i] = val
+		}
+		return arr, nil
+	case *ObjectLit:
+		obj := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			val, err := literalValue(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			obj[f.Key] = val
+		}
+		return obj, nil
+	}
+	return nil, &Diagnostic{Pos: e.Pos(), Msg: fmt.Sprintf("expected a literal value, found %s", e)}
+}
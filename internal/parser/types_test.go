@@ -0,0 +1,210 @@
+package parser
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		typ     string
+		want    interface{}
+		wantErr bool
+	}{
+		{"3", "integer", int64(3), false},
+		{" -12 ", "integer", int64(-12), false},
+		{"3.5", "integer", nil, true},
+		{"abc", "integer", nil, true},
+		{"3.5", "number", 3.5, false},
+		{"1e3", "float", 1000.0, false},
+		{"NaN", "number", nil, true},
+		{"TRUE", "boolean", true, false},
+		{"false", "boolean", false, false},
+		{"yes", "boolean", nil, true},
+		{"https://example.com/x", "url", "https://example.com/x", false},
+		{"example.com", "url", nil, true},
+		{"./data.csv", "file", "./data.csv", false},
+		{"", "file", nil, true},
+		{`[1, "a"]`, "array", []interface{}{1.0, "a"}, false},
+		{`{"a": 1}`, "object", map[string]interface{}{"a": 1.0}, false},
+		{`[1`, "array", nil, true},
+		{"hello", "string", "hello", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ+"/"+tt.value, func(t *testing.T) {
+			got, err := ConvertValue(tt.value, tt.typ)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %#v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		typ   string
+		want  interface{}
+		err   string
+	}{
+		{"whole float", 3.0, "integer", int64(3), ""},
+		{"int64", int64(math.MaxInt64), "integer", int64(math.MaxInt64), ""},
+		{"smallest integer", -9.223372036854775808e18, "integer", int64(math.MinInt64), ""},
+		{"string", "7", "integer", int64(7), ""},
+		{"fraction", 2.5, "integer", nil, "expected integer, got 2.5"},
+		{"too large", 1e19, "integer", nil, "integer out of range: 1e+19"},
+		{"too small", -1e19, "integer", nil, "integer out of range: -1e+19"},
+		{"number", int64(2), "number", 2.0, ""},
+		{"wrong type", true, "integer", nil, "expected integer, got bool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceValue(tt.value, tt.typ)
+			if tt.err != "" {
+				if err == nil || err.Error() != tt.err {
+					t.Errorf("Expected error %q, got %#v (%v)", tt.err, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %#v, got %#v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseITML_TypedDefaults(t *testing.T) {
+	dir := t.TempDir()
+
+	dsl := filepath.Join(dir, "dsl.itml")
+	src := `intent "Typed"
+inputs:
+  - days (integer) default="3"
+  - ratio (number) default=0.5
+  - pretty (boolean) default="true"
+  - tags (array) default=["a", "b"]
+`
+	if err := os.WriteFile(dsl, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	intent, err := ParseITML(dsl)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	want := []interface{}{int64(3), 0.5, true, []interface{}{"a", "b"}}
+	for i, w := range want {
+		if got := intent.Parameters[i].Default; !reflect.DeepEqual(got, w) {
+			t.Errorf("%s: expected default %#v, got %#v", intent.Parameters[i].Name, w, got)
+		}
+	}
+
+	jsonFile := filepath.Join(dir, "json.itml")
+	content := `{"name": "t", "version": "1", "description": "d",
+  "parameters": [{"name": "n", "type": "integer", "default": 4}]}`
+	if err := os.WriteFile(jsonFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	intent, err = ParseITML(jsonFile)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	if got := intent.Parameters[0].Default; got != int64(4) {
+		t.Errorf("Expected JSON default int64(4), got %#v", got)
+	}
+}
+
+func TestParseITML_InvalidDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.itml")
+	src := "intent \"Bad\"\ninputs:\n  - days (integer) default=\"three\"\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	_, err := ParseITML(path)
+	if err == nil {
+		t.Fatal("Expected error for malformed default")
+	}
+	if want := ":3:28: invalid default for input \"days\""; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected %q in %v", want, err)
+	}
+}
+
+func TestParseITML_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secret.itml")
+	src := "intent \"Weather\"\ninputs:\n  - city (string)\n  - api_key (secret) required\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	intent, err := ParseITML(path)
+	if err != nil {
+		t.Fatalf("ParseITML failed: %v", err)
+	}
+	if names := intent.SecretParameters(); len(names) != 1 || names[0] != "api_key" {
+		t.Errorf("Expected api_key to be the only secret, got %v", names)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"default", "intent \"Bad\"\ninputs:\n  - api_key (secret) default=\"abc\"\n", `:3:22: input "api_key": a secret cannot have a default`},
+		{"output", "intent \"Bad\"\noutputs:\n  - token (secret)\n", `:3:12: secret is only a type for inputs, found output "token"`},
+		{"json default", `{"name": "Bad", "version": "1", "description": "d", "parameters": [{"name": "k", "type": "secret", "default": "abc"}]}`, "parameter 0: a secret cannot have a default"},
+		{"json output", `{"name": "Bad", "version": "1", "description": "d", "outputs": [{"name": "k", "type": "secret"}]}`, "output 0: secret is only a type for inputs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.itml")
+			if err := os.WriteFile(path, []byte(tt.src), 0644); err != nil {
+				t.Fatalf("Failed to write intent: %v", err)
+			}
+			if _, err := ParseITML(path); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestParseITML_InvalidPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.itml")
+	src := "intent \"Bad\"\ninputs:\n  - code (string) pattern=\"[a-z\"\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	_, err := ParseITML(path)
+	if err == nil || !strings.Contains(err.Error(), ":3:27: input \"code\": invalid pattern") {
+		t.Errorf("Expected positioned pattern error, got %v", err)
+	}
+}
+
+func TestParseITML_InvalidCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.itml")
+	src := "intent \"Bad\"\ncache: \"1 week\"\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write intent: %v", err)
+	}
+	_, err := ParseITML(path)
+	if err == nil || !strings.Contains(err.Error(), `:2:8: invalid cache duration "1 week"`) {
+		t.Errorf("Expected positioned cache error, got %v", err)
+	}
+}